@@ -0,0 +1,394 @@
+// Command serializer inspects files produced by SaveAny,
+// SaveAnyWithOptions, and SaveContainer.
+//
+// Usage:
+//
+//     serializer dump <file>      print the tree of objects
+//     serializer stat <file>      print statistics about the objects
+//     serializer validate <file>  check that the file can be decoded
+//
+// Only the built-in types of the serializer package can be
+// decoded; other types are shown as opaque payloads.
+// Encrypted objects are also opaque, since the command has
+// no access to their keys.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/unixpickle/serializer"
+)
+
+// maxInlineElements is the number of slice elements shown
+// when rendering a primitive inline.
+const maxInlineElements = 8
+
+// encryptedTypeID is the type ID of serializer.Encrypted.
+var encryptedTypeID = (&serializer.Encrypted{}).SerializerType()
+
+// Decompressed data is limited to minDecodeBytes plus
+// maxExpansion times the size of the file, guarding
+// against decompression bombs.
+const (
+	minDecodeBytes = 1 << 20
+	maxExpansion   = 64
+)
+
+// An inspector decodes the objects in a file with limits
+// based on the file's size.
+type inspector struct {
+	registry *serializer.Registry
+
+	// budget is the number of bytes which may still be
+	// produced by unwrapping objects.
+	budget int
+}
+
+func newInspector(contents []byte) *inspector {
+	budget := minDecodeBytes + maxExpansion*len(contents)
+	registry := serializer.DefaultRegistry.Clone()
+	limits := registry.GetDecodeLimits()
+	if limits.MaxBytes == 0 || limits.MaxBytes > budget {
+		limits.MaxBytes = budget
+	}
+	registry.SetDecodeLimits(limits)
+	return &inspector{registry: registry, budget: budget}
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		usage()
+	}
+	contents, err := ioutil.ReadFile(os.Args[2])
+	if err != nil {
+		die(err)
+	}
+	switch os.Args[1] {
+	case "dump":
+		err = dump(os.Stdout, contents)
+	case "stat":
+		err = stat(os.Stdout, contents)
+	case "validate":
+		err = validate(os.Stdout, contents)
+	default:
+		usage()
+	}
+	if err != nil {
+		die(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: serializer <dump|stat|validate> <file>")
+	os.Exit(2)
+}
+
+func die(err error) {
+	fmt.Fprintln(os.Stderr, "serializer:", err)
+	os.Exit(1)
+}
+
+// A node is a single object in a file.
+type node struct {
+	Path   string
+	Depth  int
+	TypeID string
+	Data   []byte
+
+	// Err is set if the object could not be framed or
+	// unwrapped.
+	Err error
+
+	// Encrypted is set if the object is encrypted, in which
+	// case its contents are not inspected.
+	Encrypted bool
+}
+
+// readPayload strips the container (if any) from the file
+// contents.
+func (ins *inspector) readPayload(w io.Writer, contents []byte) ([]byte, error) {
+	if !serializer.IsContainer(contents) {
+		return contents, nil
+	}
+	info, payload, err := ins.registry.ReadContainer(contents)
+	if err != nil {
+		return nil, err
+	}
+	if w != nil {
+		fmt.Fprintf(w, "container: version=%d created=%s checksum=%s compression=%s\n",
+			info.Version, info.Created.Format("2006-01-02T15:04:05Z07:00"),
+			checksumName(info.Checksum), info.Compression)
+		var keys []string
+		for key := range info.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s: %s\n", key, info.Metadata[key])
+		}
+	}
+	return payload, nil
+}
+
+func checksumName(c serializer.Checksum) string {
+	switch c {
+	case serializer.ChecksumCRC32:
+		return "crc32"
+	case serializer.ChecksumSHA256:
+		return "sha256"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(c))
+	}
+}
+
+// walk visits every object in the output of
+// serializer.SerializeSlice, descending into nested
+// slices, maps, and wrappers.
+// It returns an error if the top-level framing is invalid.
+func (ins *inspector) walk(payload []byte, visit func(n *node)) error {
+	elems, err := serializer.SplitSlice(payload)
+	if err != nil {
+		return err
+	}
+	for i, elem := range elems {
+		ins.walkWithType(elem, fmt.Sprintf("[%d]", i), 0, visit)
+	}
+	return nil
+}
+
+func (ins *inspector) walkWithType(d []byte, path string, depth int,
+	visit func(n *node)) {
+	typeID, data, err := serializer.SplitWithType(d)
+	n := &node{Path: path, Depth: depth, TypeID: typeID, Data: data, Err: err}
+	if err != nil {
+		visit(n)
+		return
+	}
+
+	if typeID == "[]Serializer" {
+		elems, err := serializer.SplitSlice(data)
+		n.Err = err
+		visit(n)
+		for i, elem := range elems {
+			ins.walkWithType(elem, fmt.Sprintf("%s[%d]", path, i), depth+1, visit)
+		}
+		return
+	}
+
+	if typeID == "map[string]Serializer" {
+		elems, err := serializer.SplitSlice(data)
+		if err == nil && len(elems)%2 != 0 {
+			err = errors.New("odd number of objects")
+		}
+		n.Err = err
+		visit(n)
+		for i := 0; i+1 < len(elems); i += 2 {
+			key := fmt.Sprintf("%s[%d]", path, i)
+			if keyType, keyData, err := serializer.SplitWithType(elems[i]); err == nil &&
+				keyType == "string" {
+				key = fmt.Sprintf("%s[%q]", path, keyData)
+			}
+			ins.walkWithType(elems[i+1], key, depth+1, visit)
+		}
+		return
+	}
+
+	if typeID == encryptedTypeID {
+		n.Encrypted = true
+		visit(n)
+		return
+	}
+
+	if unwrapper := ins.registry.GetUnwrapper(typeID); unwrapper != nil {
+		inner, err := ins.unwrap(unwrapper, data)
+		n.Err = err
+		visit(n)
+		if err == nil {
+			ins.walkWithType(inner, path+"*", depth+1, visit)
+		}
+		return
+	}
+
+	visit(n)
+}
+
+// unwrap calls an Unwrapper, enforcing the limit on the
+// total amount of unwrapped data.
+func (ins *inspector) unwrap(u serializer.Unwrapper, data []byte) ([]byte, error) {
+	if ins.budget <= 0 {
+		return nil, errors.New("too much unwrapped data")
+	}
+	inner, err := u(data)
+	ins.budget -= len(inner)
+	return inner, err
+}
+
+func dump(w io.Writer, contents []byte) error {
+	ins := newInspector(contents)
+	payload, err := ins.readPayload(w, contents)
+	if err != nil {
+		return err
+	}
+	return ins.walk(payload, func(n *node) {
+		indent := strings.Repeat("  ", n.Depth)
+		fmt.Fprintf(w, "%s%s %s (%d bytes)", indent, n.Path, n.TypeID, len(n.Data))
+		if n.Err != nil {
+			fmt.Fprintf(w, " ERROR: %s", n.Err)
+		} else if n.Encrypted {
+			fmt.Fprint(w, ": (encrypted)")
+		} else if value, ok := ins.render(n); ok {
+			fmt.Fprintf(w, ": %s", value)
+		}
+		fmt.Fprintln(w)
+	})
+}
+
+func stat(w io.Writer, contents []byte) error {
+	ins := newInspector(contents)
+	payload, err := ins.readPayload(w, contents)
+	if err != nil {
+		return err
+	}
+	var numObjects, maxDepth, numErrors int
+	counts := map[string]int{}
+	sizes := map[string]int{}
+	err = ins.walk(payload, func(n *node) {
+		numObjects++
+		if n.Depth > maxDepth {
+			maxDepth = n.Depth
+		}
+		if n.Err != nil {
+			numErrors++
+		}
+		counts[n.TypeID]++
+		sizes[n.TypeID] += len(n.Data)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "file size: %d bytes\n", len(contents))
+	fmt.Fprintf(w, "payload size: %d bytes\n", len(payload))
+	fmt.Fprintf(w, "objects: %d\n", numObjects)
+	fmt.Fprintf(w, "max depth: %d\n", maxDepth)
+	fmt.Fprintf(w, "errors: %d\n", numErrors)
+
+	var typeIDs []string
+	for typeID := range counts {
+		typeIDs = append(typeIDs, typeID)
+	}
+	sort.Strings(typeIDs)
+	fmt.Fprintln(w, "types:")
+	for _, typeID := range typeIDs {
+		fmt.Fprintf(w, "  %s: %d objects, %d bytes\n", typeID, counts[typeID],
+			sizes[typeID])
+	}
+	return nil
+}
+
+func validate(w io.Writer, contents []byte) error {
+	ins := newInspector(contents)
+	payload, err := ins.readPayload(nil, contents)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	var unknown, encrypted int
+	err = ins.walk(payload, func(n *node) {
+		if n.Err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", n.Path, n.Err))
+			return
+		}
+		if n.Encrypted {
+			encrypted++
+			return
+		}
+		if !ins.decodable(n) {
+			unknown++
+			return
+		}
+		if _, err := ins.decode(n); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", n.Path, err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(w, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s)", len(problems))
+	}
+	fmt.Fprintf(w, "ok (%d object(s) of unknown type and %d encrypted object(s) not checked)\n",
+		unknown, encrypted)
+	return nil
+}
+
+// decodable checks if a node can be decoded on its own.
+// Slices, maps, and wrappers are checked through their
+// children.
+func (ins *inspector) decodable(n *node) bool {
+	if n.TypeID == "[]Serializer" || n.TypeID == "map[string]Serializer" ||
+		ins.registry.GetUnwrapper(n.TypeID) != nil {
+		return false
+	}
+	return ins.registry.Get(n.TypeID) != nil
+}
+
+func (ins *inspector) decode(n *node) (serializer.Serializer, error) {
+	return ins.registry.Get(n.TypeID)(n.Data)
+}
+
+// render formats a known primitive for display.
+func (ins *inspector) render(n *node) (string, bool) {
+	if !ins.decodable(n) {
+		return "", false
+	}
+	obj, err := ins.decode(n)
+	if err != nil {
+		return "ERROR: " + err.Error(), true
+	}
+	switch obj := obj.(type) {
+	case serializer.String:
+		return fmt.Sprintf("%q", string(obj)), true
+	case serializer.Bytes:
+		if len(obj) > maxInlineElements {
+			return fmt.Sprintf("%x... (%d bytes)", []byte(obj[:maxInlineElements]),
+				len(obj)), true
+		}
+		return fmt.Sprintf("%x", []byte(obj)), true
+	case serializer.IntSlice:
+		return renderSlice(len(obj), func(i int) interface{} { return obj[i] }), true
+	case serializer.Int32Slice:
+		return renderSlice(len(obj), func(i int) interface{} { return obj[i] }), true
+	case serializer.Int64Slice:
+		return renderSlice(len(obj), func(i int) interface{} { return obj[i] }), true
+	case serializer.Float32Slice:
+		return renderSlice(len(obj), func(i int) interface{} { return obj[i] }), true
+	case serializer.Float64Slice:
+		return renderSlice(len(obj), func(i int) interface{} { return obj[i] }), true
+	case serializer.Int, serializer.Int32, serializer.Int64, serializer.Float32,
+		serializer.Float64, serializer.Bool:
+		return fmt.Sprint(obj), true
+	default:
+		return "", false
+	}
+}
+
+func renderSlice(n int, elem func(i int) interface{}) string {
+	var parts []string
+	for i := 0; i < n && i < maxInlineElements; i++ {
+		parts = append(parts, fmt.Sprint(elem(i)))
+	}
+	if n > maxInlineElements {
+		parts = append(parts, fmt.Sprintf("... (%d elements)", n))
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/serializer"
+)
+
+func TestDump(t *testing.T) {
+	data, err := serializer.SerializeAny(
+		serializer.Int(15),
+		"hello",
+		[]serializer.Serializer{serializer.Float64Slice(make([]float64, 10))},
+		&serializer.Compressed{Codec: serializer.CodecGzip, Value: serializer.Bool(true)},
+		map[string]int{"a": 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := dump(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"[0] int (2 bytes): 15",
+		`[1] string (5 bytes): "hello"`,
+		"[2] []Serializer (109 bytes)",
+		"  [2][0] []float64 (88 bytes): [0 0 0 0 0 0 0 0 ... (10 elements)]",
+		"  [3]* bool (1 bytes): true",
+		"[4] map[string]Serializer (35 bytes)",
+		`  [4]["a"] int (1 bytes): 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data, err := serializer.SerializeAny(serializer.Int(15), serializer.Bool(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := validate(&buf, data); err != nil {
+		t.Error(err)
+	}
+
+	encrypted, err := serializer.SerializeAny(serializer.Int(15),
+		&serializer.Encrypted{KeyID: "k", Key: make([]byte, 16), Value: serializer.Bool(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := validate(&buf, encrypted); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(buf.String(), "1 encrypted object(s)") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	data[len(data)-1] = 7
+	buf.Reset()
+	if err := validate(&buf, data); err == nil {
+		t.Error("expected error for invalid bool")
+	}
+
+	buf.Reset()
+	if err := validate(&buf, data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	bomb := &serializer.Compressed{
+		Codec: serializer.CodecGzip,
+		Value: serializer.Bytes(make([]byte, minDecodeBytes*2)),
+	}
+	data, err := serializer.SerializeAny(bomb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := validate(&buf, data); err == nil {
+		t.Error("expected error for decompression bomb")
+	}
+	if !strings.Contains(buf.String(), "[0]: ") ||
+		!strings.Contains(buf.String(), "decode limit exceeded") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	var container bytes.Buffer
+	payload, err := serializer.SerializeAny(make([]byte, minDecodeBytes*2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &serializer.ContainerInfo{Compression: serializer.CodecGzip}
+	if err := serializer.WriteContainer(&container, info, payload); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := dump(&buf, container.Bytes()); err == nil {
+		t.Error("expected error for compressed container")
+	}
+}
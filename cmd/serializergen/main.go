@@ -0,0 +1,404 @@
+// Command serializergen generates Serializer
+// implementations for struct types.
+//
+// For each type, it generates a Serialize method, a
+// SerializerType method, a DeserializeXXX function, and an
+// init() function which registers the deserializer.
+// The generated code encodes the struct fields in the
+// order they are declared, so it produces the same format
+// as a hand-written serializer like
+//
+//     func (m *MyObject) Serialize() ([]byte, error) {
+//         return serializer.SerializeAny(m.Num, m.Str)
+//     }
+//
+// However, it converts each field directly rather than
+// using reflection.
+// Fields of the built-in types supported by SerializeAny
+// (such as int, []float64, and time.Time) are converted
+// to the corresponding wrapper types (such as
+// serializer.Int) and decoded with the corresponding
+// deserializers (such as serializer.DeserializeInt).
+// Fields of any other type must implement Serializer, and
+// are decoded with serializer.DeserializeWithType.
+//
+// Fields tagged with `serializer:"-"` and embedded fields
+// are skipped.
+//
+// Usage:
+//
+//     serializergen -type Type1,Type2 [-prefix path] [-output file] [dir]
+//
+// The type IDs are of the form "prefix.TypeName", where
+// prefix defaults to the import path of the package.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/unixpickle/serializer"
+)
+
+const serializerPath = "github.com/unixpickle/serializer"
+
+// A builtinField describes a field type which is encoded
+// with one of the serializer package's wrapper types.
+type builtinField struct {
+	// Wrapper is the name of the wrapper type, such as
+	// "Float64Slice".
+	Wrapper string
+
+	// TypeID is the wrapper's type ID.
+	TypeID string
+}
+
+// builtinFields maps field types (as written in the
+// source) to their wrappers, mirroring the conversions
+// of serializer.SerializeAny.
+var builtinFields = map[string]builtinField{}
+
+func init() {
+	wrappers := map[string]serializer.Serializer{
+		"string":        serializer.String(""),
+		"[]byte":        serializer.Bytes(nil),
+		"[]uint8":       serializer.Bytes(nil),
+		"int":           serializer.Int(0),
+		"[]int":         serializer.IntSlice(nil),
+		"int8":          serializer.Int8(0),
+		"[]int8":        serializer.Int8Slice(nil),
+		"int16":         serializer.Int16(0),
+		"[]int16":       serializer.Int16Slice(nil),
+		"int32":         serializer.Int32(0),
+		"[]int32":       serializer.Int32Slice(nil),
+		"int64":         serializer.Int64(0),
+		"[]int64":       serializer.Int64Slice(nil),
+		"uint":          serializer.Uint(0),
+		"[]uint":        serializer.UintSlice(nil),
+		"uint8":         serializer.Uint8(0),
+		"byte":          serializer.Uint8(0),
+		"uint16":        serializer.Uint16(0),
+		"[]uint16":      serializer.Uint16Slice(nil),
+		"uint32":        serializer.Uint32(0),
+		"[]uint32":      serializer.Uint32Slice(nil),
+		"uint64":        serializer.Uint64(0),
+		"[]uint64":      serializer.Uint64Slice(nil),
+		"float32":       serializer.Float32(0),
+		"[]float32":     serializer.Float32Slice(nil),
+		"float64":       serializer.Float64(0),
+		"[]float64":     serializer.Float64Slice(nil),
+		"complex64":     serializer.Complex64(0),
+		"[]complex64":   serializer.Complex64Slice(nil),
+		"complex128":    serializer.Complex128(0),
+		"[]complex128":  serializer.Complex128Slice(nil),
+		"bool":          serializer.Bool(false),
+		"[]bool":        serializer.BoolSlice(nil),
+		"[]string":      serializer.StringSlice(nil),
+		"[][]float32":   serializer.Float32Slice2D(nil),
+		"[][]float64":   serializer.Float64Slice2D(nil),
+		"[][]int":       serializer.IntSlice2D(nil),
+		"time.Time":     serializer.Time{},
+		"time.Duration": serializer.Duration(0),
+	}
+	for fieldType, wrapper := range wrappers {
+		builtinFields[fieldType] = builtinField{
+			Wrapper: reflect.TypeOf(wrapper).Name(),
+			TypeID:  wrapper.SerializerType(),
+		}
+	}
+}
+
+func main() {
+	var typeNames string
+	var prefix string
+	var output string
+	flag.StringVar(&typeNames, "type", "", "comma-separated list of struct types")
+	flag.StringVar(&prefix, "prefix", "", "type ID prefix (default: package import path)")
+	flag.StringVar(&output, "output", "serializer_gen.go", "output file name")
+	flag.Parse()
+
+	if typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	if prefix == "" {
+		var err error
+		prefix, err = importPath(dir)
+		if err != nil {
+			die(err)
+		}
+	}
+
+	code, err := generate(dir, strings.Split(typeNames, ","), prefix)
+	if err != nil {
+		die(err)
+	}
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(dir, output)
+	}
+	if err := ioutil.WriteFile(output, code, 0644); err != nil {
+		die(err)
+	}
+}
+
+func die(err error) {
+	fmt.Fprintln(os.Stderr, "serializergen:", err)
+	os.Exit(1)
+}
+
+func importPath(dir string) (string, error) {
+	out, err := exec.Command("go", "list", "-f", "{{.ImportPath}}", dir).Output()
+	if err != nil {
+		return "", errors.New("cannot determine import path (use -prefix): " +
+			err.Error())
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// generate produces the source code for the given types
+// in the package in dir.
+func generate(dir string, typeNames []string, prefix string) ([]byte, error) {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range pkg.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return generateFiles(pkg.Name, files, typeNames, prefix)
+}
+
+func generateFiles(pkgName string, files []*ast.File, typeNames []string,
+	prefix string) ([]byte, error) {
+	structs := map[string]*ast.StructType{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if st, ok := typeSpec.Type.(*ast.StructType); ok {
+					structs[typeSpec.Name.Name] = st
+				}
+			}
+		}
+	}
+
+	// The generated code may live in the serializer package
+	// itself, in which case it cannot import it.
+	qualifier := "serializer."
+	if prefix == serializerPath {
+		qualifier = ""
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by serializergen. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package", pkgName)
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "import (")
+	fmt.Fprintln(&buf, strconv.Quote("errors"))
+	if usesTime(structs, typeNames) {
+		fmt.Fprintln(&buf, strconv.Quote("time"))
+	}
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, strconv.Quote("github.com/unixpickle/essentials"))
+	if qualifier != "" {
+		fmt.Fprintln(&buf, strconv.Quote(serializerPath))
+	}
+	fmt.Fprintln(&buf, ")")
+
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "func init() {")
+	for _, name := range typeNames {
+		fmt.Fprintf(&buf, "%sRegisterDeserializer((&%s{}).SerializerType(),\n", qualifier, name)
+		fmt.Fprintf(&buf, "func(d []byte) (%sSerializer, error) {\n", qualifier)
+		fmt.Fprintf(&buf, "return Deserialize%s(d)\n", name)
+		fmt.Fprintln(&buf, "})")
+	}
+	fmt.Fprintln(&buf, "}")
+
+	for _, name := range typeNames {
+		st, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct type not found: %s", name)
+		}
+		fields := serializedFields(st)
+		recv := receiverName(name)
+
+		fmt.Fprintln(&buf)
+		fmt.Fprintf(&buf, "// Deserialize%s deserializes a %s.\n", name, name)
+		fmt.Fprintf(&buf, "func Deserialize%s(d []byte) (res *%s, err error) {\n", name, name)
+		fmt.Fprintf(&buf, "defer essentials.AddCtxTo(%s, &err)\n",
+			strconv.Quote("deserialize "+name))
+		fmt.Fprintf(&buf, "elems, err := %sSplitSlice(d)\n", qualifier)
+		fmt.Fprintln(&buf, "if err != nil {")
+		fmt.Fprintln(&buf, "return nil, err")
+		fmt.Fprintln(&buf, "}")
+		fmt.Fprintf(&buf, "if len(elems) != %d {\n", len(fields))
+		fmt.Fprintf(&buf, "return nil, errors.New(%s)\n",
+			strconv.Quote(fmt.Sprintf("expected %d fields", len(fields))))
+		fmt.Fprintln(&buf, "}")
+		fmt.Fprintf(&buf, "res = &%s{}\n", name)
+		for i, field := range fields {
+			writeFieldDecoder(&buf, qualifier, field, i)
+		}
+		fmt.Fprintln(&buf, "return res, nil")
+		fmt.Fprintln(&buf, "}")
+
+		fmt.Fprintln(&buf)
+		fmt.Fprintln(&buf, "// SerializerType returns the unique ID used to serialize")
+		fmt.Fprintf(&buf, "// a %s.\n", name)
+		fmt.Fprintf(&buf, "func (%s *%s) SerializerType() string {\n", recv, name)
+		fmt.Fprintf(&buf, "return %s\n", strconv.Quote(prefix+"."+name))
+		fmt.Fprintln(&buf, "}")
+
+		fmt.Fprintln(&buf)
+		fmt.Fprintln(&buf, "// Serialize serializes the object.")
+		fmt.Fprintf(&buf, "func (%s *%s) Serialize() ([]byte, error) {\n", recv, name)
+		fmt.Fprintf(&buf, "return %sSerializeSlice([]%sSerializer{\n", qualifier, qualifier)
+		for _, field := range fields {
+			if builtin, ok := builtinFields[field.Type]; ok {
+				fmt.Fprintf(&buf, "%s%s(%s.%s),\n", qualifier, builtin.Wrapper, recv,
+					field.Name)
+			} else {
+				fmt.Fprintf(&buf, "%s.%s,\n", recv, field.Name)
+			}
+		}
+		fmt.Fprintln(&buf, "})")
+		fmt.Fprintln(&buf, "}")
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// A structField is a serialized field of a struct.
+type structField struct {
+	Name string
+
+	// Type is the field's type as written in the source.
+	Type string
+}
+
+// serializedFields returns the fields in a struct which
+// should be serialized.
+func serializedFields(st *ast.StructType) []structField {
+	var res []structField
+	for _, field := range st.Fields.List {
+		if field.Tag != nil {
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err == nil {
+				value := reflect.StructTag(tag).Get("serializer")
+				if strings.Split(value, ",")[0] == "-" {
+					continue
+				}
+			}
+		}
+		for _, name := range field.Names {
+			if name.Name != "_" {
+				res = append(res, structField{
+					Name: name.Name,
+					Type: types.ExprString(field.Type),
+				})
+			}
+		}
+	}
+	return res
+}
+
+// writeFieldDecoder generates code which decodes elems[i]
+// into the given field of res.
+//
+// Built-in types are decoded with their typed
+// deserializers, while other types are decoded with
+// DeserializeWithType.
+func writeFieldDecoder(w io.Writer, qualifier string, field structField, i int) {
+	ctx := strconv.Quote("field " + field.Name)
+	fmt.Fprintln(w, "{")
+	builtin, ok := builtinFields[field.Type]
+	if ok {
+		fmt.Fprintf(w, "typeID, data, err := %sSplitWithType(elems[%d])\n", qualifier, i)
+		fmt.Fprintln(w, "if err != nil {")
+		fmt.Fprintf(w, "return nil, essentials.AddCtx(%s, err)\n", ctx)
+		fmt.Fprintln(w, "}")
+		fmt.Fprintf(w, "if typeID != %s {\n", strconv.Quote(builtin.TypeID))
+		fmt.Fprintf(w, "return nil, essentials.AddCtx(%s, "+
+			"errors.New(\"unexpected type ID: \"+typeID))\n", ctx)
+		fmt.Fprintln(w, "}")
+		fmt.Fprintf(w, "obj, err := %sDeserialize%s(data)\n", qualifier, builtin.Wrapper)
+		fmt.Fprintln(w, "if err != nil {")
+		fmt.Fprintf(w, "return nil, essentials.AddCtx(%s, err)\n", ctx)
+		fmt.Fprintln(w, "}")
+		if builtin.Wrapper == "Bytes" {
+			// DeserializeBytes does not copy the data.
+			fmt.Fprintf(w, "res.%s = append([]byte{}, obj...)\n", field.Name)
+		} else {
+			fmt.Fprintf(w, "res.%s = %s(obj)\n", field.Name, field.Type)
+		}
+	} else {
+		fmt.Fprintf(w, "obj, err := %sDeserializeWithType(elems[%d])\n", qualifier, i)
+		fmt.Fprintln(w, "if err != nil {")
+		fmt.Fprintf(w, "return nil, essentials.AddCtx(%s, err)\n", ctx)
+		fmt.Fprintln(w, "}")
+		fmt.Fprintf(w, "value, ok := obj.(%s)\n", field.Type)
+		fmt.Fprintln(w, "if !ok {")
+		fmt.Fprintf(w, "return nil, essentials.AddCtx(%s, "+
+			"errors.New(\"unexpected type ID: \"+obj.SerializerType()))\n", ctx)
+		fmt.Fprintln(w, "}")
+		fmt.Fprintf(w, "res.%s = value\n", field.Name)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// usesTime checks if any of the types have fields of
+// built-in types from the time package.
+func usesTime(structs map[string]*ast.StructType, typeNames []string) bool {
+	for _, name := range typeNames {
+		if st, ok := structs[name]; ok {
+			for _, field := range serializedFields(st) {
+				if _, ok := builtinFields[field.Type]; ok &&
+					strings.HasPrefix(field.Type, "time.") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func receiverName(typeName string) string {
+	for _, ch := range typeName {
+		return string(unicode.ToLower(ch))
+	}
+	return "x"
+}
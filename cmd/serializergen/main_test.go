@@ -0,0 +1,165 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package foo
+
+type Point struct {
+	X, Y    float64
+	Label   string
+	cache   []int ` + "`serializer:\"-\"`" + `
+	Embedded
+}
+`
+
+func TestGenerateFiles(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo.go", testSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := generateFiles("foo", []*ast.File{file}, []string{"Point"},
+		"example.com/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(code)
+	for _, expected := range []string{
+		"serializer.RegisterDeserializer((&Point{}).SerializerType(),",
+		"obj, err := serializer.DeserializeFloat64(data)",
+		"res.Label = string(obj)",
+		"serializer.Float64(p.X),",
+		"serializer.String(p.Label),",
+		`return "example.com/foo.Point"`,
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("missing %q in:\n%s", expected, src)
+		}
+	}
+	for _, unexpected := range []string{"SerializeAny", "DeserializeAny", "cache"} {
+		if strings.Contains(src, unexpected) {
+			t.Errorf("unexpected %q in:\n%s", unexpected, src)
+		}
+	}
+	if _, err := parser.ParseFile(fset, "gen.go", code, 0); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := generateFiles("foo", []*ast.File{file}, []string{"Missing"},
+		"example.com/foo"); err == nil {
+		t.Error("expected error for missing type")
+	}
+}
+
+const roundTripSource = `package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/unixpickle/serializer"
+)
+
+type Inner struct {
+	Values [][]float64
+}
+
+type Outer struct {
+	Num     int
+	Data    []byte
+	Weights []float32
+	Created time.Time
+	Timeout time.Duration
+	Inner   *Inner
+	Any     serializer.Serializer
+}
+
+func main() {
+	obj := &Outer{
+		Num:     -3,
+		Data:    []byte("abc"),
+		Weights: []float32{1, 2.5},
+		Created: time.Unix(1234, 5).UTC(),
+		Timeout: time.Second,
+		Inner:   &Inner{Values: [][]float64{{1}, {}}},
+		Any:     serializer.String("x"),
+	}
+	data, err := obj.Serialize()
+	if err != nil {
+		fail(err)
+	}
+	expected, err := serializer.SerializeAny(obj.Num, obj.Data, obj.Weights, obj.Created,
+		obj.Timeout, obj.Inner, obj.Any)
+	if err != nil {
+		fail(err)
+	}
+	if !bytes.Equal(data, expected) {
+		fail("data differs from SerializeAny")
+	}
+	full, err := serializer.SerializeWithType(obj)
+	if err != nil {
+		fail(err)
+	}
+	decoded, err := serializer.DeserializeWithType(full)
+	if err != nil {
+		fail(err)
+	}
+	if !reflect.DeepEqual(decoded, obj) {
+		fail(fmt.Sprintf("expected %v but got %v", obj, decoded))
+	}
+	if _, err := DeserializeOuter(data[:len(data)-1]); err == nil {
+		fail("expected error for truncated data")
+	}
+}
+
+func fail(msg interface{}) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
+`
+
+func TestGeneratedRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+
+	// The package must be inside the source tree so that it
+	// can import the serializer package.
+	dir, err := ioutil.TempDir(".", "roundtrip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(roundTripSource),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	code, err := generate(dir, []string{"Inner", "Outer"}, "example.com/roundtrip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "serializer_gen.go"), code,
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"vet"}, {"run"}} {
+		cmd := exec.Command("go", append(args, "./"+filepath.Base(dir))...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %s\n%s\n%s", args[0], err, out, code)
+		}
+	}
+}
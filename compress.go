@@ -0,0 +1,169 @@
+package serializer
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	for _, codec := range []Codec{CodecNone, CodecGzip, CodecFlate, CodecZlib} {
+		codec := codec
+		RegisterContextUnwrapper(codec.typeID(), func(d []byte,
+			ctx *DecodeContext) ([]byte, error) {
+			return decompress(codec, d, ctx.Limits())
+		})
+	}
+}
+
+// A Codec is a compression algorithm.
+type Codec uint8
+
+const (
+	CodecNone Codec = iota
+	CodecGzip
+	CodecFlate
+	CodecZlib
+)
+
+// String returns the name of the codec.
+func (c Codec) String() string {
+	switch c {
+	case CodecNone:
+		return "none"
+	case CodecGzip:
+		return "gzip"
+	case CodecFlate:
+		return "flate"
+	case CodecZlib:
+		return "zlib"
+	default:
+		return fmt.Sprintf("Codec(%d)", int(c))
+	}
+}
+
+func (c Codec) typeID() string {
+	return "compressed/" + c.String()
+}
+
+// NewCompressWriter creates a writer which compresses data
+// with the codec and writes it to w.
+//
+// The writer must be closed to flush the compressed data.
+// Closing it does not close w.
+//
+// This can be combined with an Encoder to compress an
+// entire stream of objects.
+func NewCompressWriter(w io.Writer, c Codec) (io.WriteCloser, error) {
+	switch c {
+	case CodecNone:
+		return nopWriteCloser{w}, nil
+	case CodecGzip:
+		return gzip.NewWriter(w), nil
+	case CodecFlate:
+		return flate.NewWriter(w, flate.DefaultCompression)
+	case CodecZlib:
+		return zlib.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown codec: %s", c)
+	}
+}
+
+// NewDecompressReader creates a reader which decompresses
+// data from r using the codec.
+//
+// This can be combined with a Decoder to read a stream
+// written with NewCompressWriter.
+func NewDecompressReader(r io.Reader, c Codec) (io.ReadCloser, error) {
+	switch c {
+	case CodecNone:
+		return ioutil.NopCloser(r), nil
+	case CodecGzip:
+		return gzip.NewReader(r)
+	case CodecFlate:
+		return flate.NewReader(r), nil
+	case CodecZlib:
+		return zlib.NewReader(r)
+	default:
+		return nil, fmt.Errorf("unknown codec: %s", c)
+	}
+}
+
+// Compressed is a Serializer which compresses another
+// Serializer.
+//
+// The codec is stored in the type ID, and the registered
+// unwrappers decompress the data automatically.
+// Thus, DeserializeWithType on a serialized Compressed
+// yields the original Value rather than a Compressed.
+type Compressed struct {
+	Codec Codec
+	Value Serializer
+}
+
+// Serialize serializes the object.
+func (c *Compressed) Serialize() (data []byte, err error) {
+	defer essentials.AddCtxTo("serialize compressed", &err)
+	inner, err := SerializeWithType(c.Value)
+	if err != nil {
+		return nil, err
+	}
+	return compress(c.Codec, inner)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Compressed with its codec.
+func (c *Compressed) SerializerType() string {
+	return c.Codec.typeID()
+}
+
+func compress(c Codec, d []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewCompressWriter(&buf, c)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(d); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(c Codec, d []byte, limits DecodeLimits) (res []byte, err error) {
+	defer essentials.AddCtxTo("decompress "+c.String(), &err)
+	r, err := NewDecompressReader(bytes.NewReader(d), c)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// Guard against decompression bombs.
+	if limits.MaxBytes > 0 {
+		r = ioutil.NopCloser(io.LimitReader(r, int64(limits.MaxBytes)+1))
+	}
+	res, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.checkBytes(uint64(len(res))); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (n nopWriteCloser) Close() error {
+	return nil
+}
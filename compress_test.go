@@ -0,0 +1,88 @@
+package serializer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCompressed(t *testing.T) {
+	value := make(Float64Slice, 1000)
+	for _, codec := range []Codec{CodecNone, CodecGzip, CodecFlate, CodecZlib} {
+		compressed := &Compressed{Codec: codec, Value: value}
+		data, err := SerializeWithType(compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if codec != CodecNone && len(data) > 1000 {
+			t.Errorf("codec %s: data was not compressed (%d bytes)", codec, len(data))
+		}
+		obj, err := DeserializeWithType(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(obj, value) {
+			t.Errorf("codec %s: unexpected result", codec)
+		}
+	}
+}
+
+func TestCompressStream(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewCompressWriter(&buf, CodecGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := NewEncoder(w)
+	if err := enc.Encode(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewDecompressReader(&buf, CodecGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoder(r)
+	var x int
+	var s string
+	if err := dec.DecodeInto(&x); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.DecodeInto(&s); err != nil {
+		t.Fatal(err)
+	}
+	if x != 3 || s != "hello" {
+		t.Errorf("unexpected result: %d %s", x, s)
+	}
+}
+
+func TestCompressedContainer(t *testing.T) {
+	payload, err := SerializeAny(make([]float32, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	info := &ContainerInfo{Compression: CodecZlib}
+	if err := WriteContainer(&buf, info, payload); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > 1000 {
+		t.Errorf("data was not compressed (%d bytes)", buf.Len())
+	}
+	actualInfo, actualPayload, err := ReadContainer(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actualInfo.Compression != CodecZlib {
+		t.Errorf("unexpected codec: %s", actualInfo.Compression)
+	}
+	if !bytes.Equal(actualPayload, payload) {
+		t.Error("payload mismatch")
+	}
+}
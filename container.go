@@ -0,0 +1,329 @@
+package serializer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/unixpickle/essentials"
+)
+
+// containerMagic starts every container.
+// It can never be mistaken for the start of a legacy file,
+// since a legacy file starts with the little-endian size
+// of its first object and this would decode as an
+// impossibly large size.
+const containerMagic = "\x89SRLZ\r\n\x1a"
+
+const containerVersion = 1
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// A Checksum identifies the integrity check stored at the
+// end of a container.
+type Checksum uint8
+
+const (
+	ChecksumCRC32 Checksum = iota
+	ChecksumSHA256
+)
+
+func (c Checksum) newHash() (hash.Hash, error) {
+	switch c {
+	case ChecksumCRC32:
+		return crc32.NewIEEE(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown checksum: %d", c)
+	}
+}
+
+// ContainerInfo stores the header fields of a container.
+type ContainerInfo struct {
+	// Version is the container format version.
+	// It is ignored when writing a container.
+	Version int
+
+	// Created is the time when the container was created.
+	// If it is zero when writing a container, the current
+	// time is used.
+	Created time.Time
+
+	// Metadata stores arbitrary key-value pairs.
+	Metadata map[string]string
+
+	// Checksum is the integrity check used for the header
+	// and payload.
+	Checksum Checksum
+
+	// Compression is the codec used to compress the
+	// payload.
+	Compression Codec
+}
+
+// IsContainer checks if the data starts with a container
+// header.
+func IsContainer(d []byte) bool {
+	return bytes.HasPrefix(d, []byte(containerMagic))
+}
+
+// WriteContainer wraps a payload (e.g. the result of
+// SerializeAny) in a container and writes it to w.
+//
+// The container consists of a header with a magic number,
+// a format version, and the info fields, followed by the
+// (possibly compressed) payload and a checksum of
+// everything before it.
+//
+// If info is nil, default values are used.
+func WriteContainer(w io.Writer, info *ContainerInfo, payload []byte) (err error) {
+	defer essentials.AddCtxTo("write container", &err)
+
+	if info == nil {
+		info = &ContainerInfo{}
+	}
+	created := info.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	h, err := info.Checksum.newHash()
+	if err != nil {
+		return err
+	}
+	if info.Compression != CodecNone {
+		payload, err = compress(info.Compression, payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	var header bytes.Buffer
+	header.WriteString(containerMagic)
+	writeUint16(&header, containerVersion)
+	header.WriteByte(byte(info.Checksum))
+	header.WriteByte(byte(info.Compression))
+
+	writeUint64(&header, uint64(created.UnixNano()))
+
+	var keys []string
+	for key := range info.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	writeUint32(&header, uint32(len(keys)))
+	for _, key := range keys {
+		writeString32(&header, key)
+		writeString32(&header, info.Metadata[key])
+	}
+	writeUint64(&header, uint64(len(payload)))
+
+	out := io.MultiWriter(w, h)
+	if _, err := out.Write(header.Bytes()); err != nil {
+		return err
+	}
+	if _, err := out.Write(payload); err != nil {
+		return err
+	}
+	_, err = w.Write(h.Sum(nil))
+	return err
+}
+
+// ReadContainer decodes a container, verifying its
+// checksum and decompressing its payload.
+//
+// If the payload is not compressed, the returned payload
+// is a sub-slice of d.
+// Otherwise, the decompressed size is limited by the
+// DecodeLimits of the DefaultRegistry.
+func ReadContainer(d []byte) (info *ContainerInfo, payload []byte, err error) {
+	return readContainer(d, GetDecodeLimits())
+}
+
+// ReadContainer is like the global ReadContainer, but the
+// decompressed size is limited by the DecodeLimits of r.
+func (r *Registry) ReadContainer(d []byte) (info *ContainerInfo, payload []byte,
+	err error) {
+	return readContainer(d, r.GetDecodeLimits())
+}
+
+func readContainer(d []byte, limits DecodeLimits) (info *ContainerInfo, payload []byte,
+	err error) {
+	defer essentials.AddCtxTo("read container", &err)
+
+	if !IsContainer(d) {
+		return nil, nil, errors.New("missing container header")
+	}
+	r := &byteReader{data: d, offset: len(containerMagic)}
+
+	version, err := r.Uint16()
+	if err != nil {
+		return nil, nil, err
+	}
+	if version != containerVersion {
+		return nil, nil, fmt.Errorf("unsupported container version: %d", version)
+	}
+	info = &ContainerInfo{Version: int(version)}
+
+	flags, err := r.Next(2)
+	if err != nil {
+		return nil, nil, err
+	}
+	info.Checksum = Checksum(flags[0])
+	info.Compression = Codec(flags[1])
+	h, err := info.Checksum.newHash()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	created, err := r.Uint64()
+	if err != nil {
+		return nil, nil, err
+	}
+	info.Created = time.Unix(0, int64(created))
+
+	numMeta, err := r.Uint32()
+	if err != nil {
+		return nil, nil, err
+	}
+	if numMeta > 0 {
+		info.Metadata = map[string]string{}
+	}
+	for i := 0; i < int(numMeta); i++ {
+		key, err := r.String32()
+		if err != nil {
+			return nil, nil, err
+		}
+		value, err := r.String32()
+		if err != nil {
+			return nil, nil, err
+		}
+		info.Metadata[key] = value
+	}
+
+	payloadSize, err := r.Uint64()
+	if err != nil {
+		return nil, nil, err
+	}
+	if payloadSize > uint64(r.Remaining()) {
+		return nil, nil, ErrBufferUnderflow
+	}
+	payload, _ = r.Next(int(payloadSize))
+
+	h.Write(d[:r.offset])
+	checksum, err := r.Next(h.Size())
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.Remaining() != 0 {
+		return nil, nil, ErrResidualData
+	}
+	if !bytes.Equal(checksum, h.Sum(nil)) {
+		return nil, nil, ErrChecksumMismatch
+	}
+	if info.Compression != CodecNone {
+		payload, err = decompress(info.Compression, payload, limits)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return info, payload, nil
+}
+
+// SaveContainer is like SaveAny, but it wraps the data in
+// a container with the given info (which may be nil).
+//
+// The resulting file can be read with LoadAny.
+func SaveContainer(path string, info *ContainerInfo, obj ...interface{}) (err error) {
+	defer essentials.AddCtxTo("SaveContainer", &err)
+	if info == nil {
+		info = &ContainerInfo{}
+	}
+	return SaveAnyWithOptions(path, &SaveOptions{Container: info}, obj...)
+}
+
+func writeUint16(w *bytes.Buffer, x uint16) {
+	var buf [2]byte
+	helperByteOrder.PutUint16(buf[:], x)
+	w.Write(buf[:])
+}
+
+func writeUint32(w *bytes.Buffer, x uint32) {
+	var buf [4]byte
+	helperByteOrder.PutUint32(buf[:], x)
+	w.Write(buf[:])
+}
+
+func writeUint64(w *bytes.Buffer, x uint64) {
+	var buf [8]byte
+	helperByteOrder.PutUint64(buf[:], x)
+	w.Write(buf[:])
+}
+
+func writeString32(w *bytes.Buffer, s string) {
+	writeUint32(w, uint32(len(s)))
+	w.WriteString(s)
+}
+
+// byteReader reads fields from a buffer without copying,
+// failing with ErrBufferUnderflow at the end of the data.
+type byteReader struct {
+	data   []byte
+	offset int
+}
+
+func (b *byteReader) Remaining() int {
+	return len(b.data) - b.offset
+}
+
+func (b *byteReader) Next(n int) ([]byte, error) {
+	if n < 0 || n > b.Remaining() {
+		return nil, ErrBufferUnderflow
+	}
+	res := b.data[b.offset : b.offset+n]
+	b.offset += n
+	return res, nil
+}
+
+func (b *byteReader) Uint16() (uint16, error) {
+	d, err := b.Next(2)
+	if err != nil {
+		return 0, err
+	}
+	return helperByteOrder.Uint16(d), nil
+}
+
+func (b *byteReader) Uint32() (uint32, error) {
+	d, err := b.Next(4)
+	if err != nil {
+		return 0, err
+	}
+	return helperByteOrder.Uint32(d), nil
+}
+
+func (b *byteReader) Uint64() (uint64, error) {
+	d, err := b.Next(8)
+	if err != nil {
+		return 0, err
+	}
+	return helperByteOrder.Uint64(d), nil
+}
+
+func (b *byteReader) String32() (string, error) {
+	size, err := b.Uint32()
+	if err != nil {
+		return "", err
+	}
+	if uint64(size) > uint64(b.Remaining()) {
+		return "", ErrBufferUnderflow
+	}
+	d, _ := b.Next(int(size))
+	return string(d), nil
+}
@@ -0,0 +1,115 @@
+package serializer
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestContainer(t *testing.T) {
+	payload, err := SerializeAny(Int(3), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, checksum := range []Checksum{ChecksumCRC32, ChecksumSHA256} {
+		info := &ContainerInfo{
+			Created:  time.Unix(1500000000, 123),
+			Metadata: map[string]string{"b": "2", "a": "1"},
+			Checksum: checksum,
+		}
+		var buf bytes.Buffer
+		if err := WriteContainer(&buf, info, payload); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		if !IsContainer(data) {
+			t.Fatal("container not detected")
+		}
+		if IsContainer(payload) {
+			t.Fatal("raw data detected as container")
+		}
+
+		actualInfo, actualPayload, err := ReadContainer(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(actualPayload, payload) {
+			t.Error("payload mismatch")
+		}
+		info.Version = containerVersion
+		if !actualInfo.Created.Equal(info.Created) {
+			t.Errorf("expected time %v but got %v", info.Created, actualInfo.Created)
+		}
+		actualInfo.Created = info.Created
+		if !reflect.DeepEqual(actualInfo, info) {
+			t.Errorf("expected info %v but got %v", info, actualInfo)
+		}
+
+		for i := range data {
+			corrupted := append([]byte{}, data...)
+			corrupted[i] ^= 0x10
+			if _, _, err := ReadContainer(corrupted); err == nil {
+				t.Errorf("corruption at byte %d not detected", i)
+			}
+		}
+		for i := 0; i < len(data); i++ {
+			if _, _, err := ReadContainer(data[:i]); err == nil {
+				t.Errorf("truncation at byte %d not detected", i)
+			}
+		}
+	}
+}
+
+func TestRegistryReadContainer(t *testing.T) {
+	payload := make([]byte, 1000)
+	info := &ContainerInfo{Compression: CodecGzip}
+	var buf bytes.Buffer
+	if err := WriteContainer(&buf, info, payload); err != nil {
+		t.Fatal(err)
+	}
+	r := DefaultRegistry.Clone()
+	r.SetDecodeLimits(DecodeLimits{MaxBytes: 500})
+	_, _, err := r.ReadContainer(buf.Bytes())
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxBytes" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, actual, err := ReadContainer(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(actual, payload) {
+		t.Error("payload mismatch")
+	}
+}
+
+func TestLoadAnyContainer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serializer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rawPath := filepath.Join(dir, "raw")
+	containerPath := filepath.Join(dir, "container")
+	if err := SaveAny(rawPath, 3, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveContainer(containerPath, nil, 3, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{rawPath, containerPath} {
+		var x int
+		var s string
+		if err := LoadAny(path, &x, &s); err != nil {
+			t.Fatal(err)
+		}
+		if x != 3 || s != "hello" {
+			t.Errorf("unexpected result: %d %s", x, s)
+		}
+	}
+}
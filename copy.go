@@ -2,19 +2,41 @@ package serializer
 
 import (
 	"fmt"
+	"reflect"
+	"unsafe"
 
 	"github.com/unixpickle/essentials"
 )
 
 // A Copier can produce a deep copy of itself.
+//
+// Immutable types, such as the built-in scalar types
+// (String, Int, Float64, Time, etc.), implement Copy by
+// returning the object itself.
 type Copier interface {
 	Copy() (interface{}, error)
 }
 
+var (
+	copierType     = reflect.TypeOf((*Copier)(nil)).Elem()
+	serializerType = reflect.TypeOf((*Serializer)(nil)).Elem()
+)
+
 // Copy produces a copy of the object.
 //
 // This uses the Copier interface if possible and falls
 // back on using Serializer if necessary.
+//
+// Other objects, such as plain structs, slices, maps, and
+// pointers, are copied recursively using reflection,
+// including their unexported fields.
+// Nested values which implement Copier or Serializer are
+// copied using those interfaces instead.
+// Pointers, maps, and slices which appear several times
+// in the object are copied once, so shared references and
+// cycles are preserved in the copy.
+// Channels and functions cannot be copied unless they are
+// nil.
 func Copy(obj interface{}) (copied interface{}, err error) {
 	defer essentials.AddCtxTo("copy", &err)
 	if copier, ok := obj.(Copier); ok {
@@ -25,7 +47,394 @@ func Copy(obj interface{}) (copied interface{}, err error) {
 			return nil, err
 		}
 		return DeserializeWithType(data)
+	} else if obj == nil {
+		return nil, nil
+	} else {
+		c := &deepCopier{visited: map[graphKey]reflect.Value{}}
+		res, err := c.copyReflect(reflect.ValueOf(obj))
+		if err != nil {
+			return nil, err
+		}
+		return res.Interface(), nil
+	}
+}
+
+// deepCopier implements the reflection-based fallback of
+// Copy.
+type deepCopier struct {
+	visited map[graphKey]reflect.Value
+}
+
+// copy copies a value, preferring the Copier and
+// Serializer interfaces.
+func (d *deepCopier) copy(val reflect.Value) (reflect.Value, error) {
+	t := val.Type()
+	if val.Kind() == reflect.Interface ||
+		(!t.Implements(copierType) && !t.Implements(serializerType)) {
+		return d.copyReflect(val)
+	}
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if val.IsNil() {
+			return reflect.Zero(t), nil
+		}
+	}
+	key, shared := identityKey(val)
+	if shared {
+		if res, ok := d.visited[key]; ok {
+			return res, nil
+		}
+	}
+	copied, err := Copy(val.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	res := reflect.ValueOf(copied)
+	if !res.IsValid() {
+		res = reflect.Zero(t)
+	} else if !res.Type().AssignableTo(t) {
+		if !res.Type().ConvertibleTo(t) {
+			return reflect.Value{}, fmt.Errorf("copy of %s produced %T", t, copied)
+		}
+		res = res.Convert(t)
+	}
+	if shared {
+		d.visited[key] = res
+	}
+	return res, nil
+}
+
+// copyReflect copies a value using reflection.
+func (d *deepCopier) copyReflect(val reflect.Value) (reflect.Value, error) {
+	t := val.Type()
+	switch val.Kind() {
+	case reflect.Ptr:
+		if val.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		key, _ := identityKey(val)
+		if res, ok := d.visited[key]; ok {
+			return res, nil
+		}
+		res := reflect.New(t.Elem())
+		d.visited[key] = res
+		elem, err := d.copy(val.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		res.Elem().Set(elem)
+		return res, nil
+	case reflect.Interface:
+		if val.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		elem, err := d.copy(val.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		res := reflect.New(t).Elem()
+		res.Set(elem)
+		return res, nil
+	case reflect.Struct:
+		if !val.CanAddr() {
+			addressable := reflect.New(t).Elem()
+			addressable.Set(val)
+			val = addressable
+		}
+		res := reflect.New(t).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			field, err := d.copy(exposeField(val.Field(i)))
+			if err != nil {
+				return reflect.Value{}, essentials.AddCtx("field "+t.Field(i).Name, err)
+			}
+			exposeField(res.Field(i)).Set(field)
+		}
+		return res, nil
+	case reflect.Array:
+		res := reflect.New(t).Elem()
+		for i := 0; i < val.Len(); i++ {
+			elem, err := d.copy(val.Index(i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			res.Index(i).Set(elem)
+		}
+		return res, nil
+	case reflect.Slice:
+		if val.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		key, shared := identityKey(val)
+		if shared {
+			if res, ok := d.visited[key]; ok {
+				return res, nil
+			}
+		}
+		res := reflect.MakeSlice(t, val.Len(), val.Len())
+		if shared {
+			d.visited[key] = res
+		}
+		for i := 0; i < val.Len(); i++ {
+			elem, err := d.copy(val.Index(i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			res.Index(i).Set(elem)
+		}
+		return res, nil
+	case reflect.Map:
+		if val.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		key, shared := identityKey(val)
+		if shared {
+			if res, ok := d.visited[key]; ok {
+				return res, nil
+			}
+		}
+		res := reflect.MakeMapWithSize(t, val.Len())
+		if shared {
+			d.visited[key] = res
+		}
+		iter := val.MapRange()
+		for iter.Next() {
+			k, err := d.copy(iter.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v, err := d.copy(iter.Value())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			res.SetMapIndex(k, v)
+		}
+		return res, nil
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if val.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot copy objects of type %s", t)
+	default:
+		res := reflect.New(t).Elem()
+		res.Set(val)
+		return res, nil
+	}
+}
+
+// exposeField makes an addressable struct field usable
+// even if it is unexported.
+func exposeField(field reflect.Value) reflect.Value {
+	if field.CanInterface() {
+		return field
+	}
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+// CopyInto copies src into the variable pointed to by dst,
+// which must have the same type as src.
+//
+// Unlike Copy, CopyInto reuses the memory of the current
+// destination value where possible.
+// This applies to slices of numbers, bools, and strings
+// (including wrappers like Float64Slice), two-dimensional
+// slices like Float64Slice2D, []Serializer and Map values
+// (whose elements are copied into the old elements of the
+// same type), and Tensors.
+// Only the elements within the length of a destination
+// slice are reused, although its spare capacity may be
+// overwritten.
+// Other values are copied with Copy.
+//
+// The old destination value is overwritten, so it must
+// not be in use elsewhere.
+func CopyInto(dst, src interface{}) (err error) {
+	defer essentials.AddCtxTo("copy into", &err)
+	dstVal := reflect.ValueOf(dst)
+	if dstVal.Kind() != reflect.Ptr || dstVal.IsNil() {
+		return fmt.Errorf("expected non-nil pointer but got %T", dst)
+	}
+	dstVal = dstVal.Elem()
+	srcVal := reflect.ValueOf(src)
+	if !srcVal.IsValid() {
+		dstVal.Set(reflect.Zero(dstVal.Type()))
+		return nil
+	} else if srcVal.Type() != dstVal.Type() {
+		return fmt.Errorf("cannot copy %T into %s", src, dstVal.Type())
+	}
+
+	switch src := src.(type) {
+	case *Tensor:
+		return copyTensorInto(dst.(**Tensor), src)
+	case slice:
+		return copySliceInto(dst.(*slice), src)
+	case Map:
+		return copyMapInto(dst.(*Map), src)
+	}
+	if copyFlatInto(dstVal, srcVal) {
+		return nil
+	}
+
+	copied, err := Copy(src)
+	if err != nil {
+		return err
+	}
+	copiedVal := reflect.ValueOf(copied)
+	if !copiedVal.IsValid() {
+		copiedVal = reflect.Zero(dstVal.Type())
+	} else if copiedVal.Type() != dstVal.Type() {
+		return fmt.Errorf("copy of %T produced %T", src, copied)
+	}
+	dstVal.Set(copiedVal)
+	return nil
+}
+
+// copyFlatInto implements CopyInto for slices of plain
+// values and slices of such slices.
+// It returns false for other types.
+func copyFlatInto(dst, src reflect.Value) bool {
+	if src.Kind() != reflect.Slice {
+		return false
+	}
+	elemType := src.Type().Elem()
+	if isPlainKind(elemType.Kind()) {
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+		} else if dst.IsNil() {
+			dst.Set(reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, src.Len()), src))
+		} else {
+			dst.Set(reflect.AppendSlice(dst.Slice(0, 0), src))
+		}
+		return true
+	} else if elemType.Kind() != reflect.Slice || !isPlainKind(elemType.Elem().Kind()) {
+		return false
+	}
+	if src.IsNil() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return true
+	}
+	rows := dst
+	if dst.Len() >= src.Len() {
+		rows = dst.Slice(0, src.Len())
 	} else {
-		return nil, fmt.Errorf("cannot copy objects of type %T", obj)
+		extra := reflect.MakeSlice(dst.Type(), src.Len()-dst.Len(), src.Len()-dst.Len())
+		rows = reflect.AppendSlice(dst, extra)
+	}
+	for i := 0; i < src.Len(); i++ {
+		copyFlatInto(rows.Index(i), src.Index(i))
+	}
+	dst.Set(rows)
+	return true
+}
+
+func isPlainKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+func copyTensorInto(dst **Tensor, src *Tensor) error {
+	if src == nil {
+		*dst = nil
+		return nil
+	} else if *dst == src {
+		return nil
+	} else if *dst == nil {
+		*dst = &Tensor{}
+	}
+	c := src.Contiguous()
+	t := *dst
+	t.DType = c.DType
+	t.Offset = 0
+	t.Shape = copyInts(t.Shape, c.Shape)
+	t.Strides = copyInts(t.Strides, c.Strides)
+	t.Data = append(t.Data[:0], c.Data...)
+	return nil
+}
+
+func copyInts(dst, src []int) []int {
+	if src == nil {
+		return nil
+	}
+	return append(dst[:0], src...)
+}
+
+func copySliceInto(dst *slice, src slice) error {
+	if src == nil {
+		*dst = nil
+		return nil
+	}
+	res := *dst
+	if len(res) >= len(src) {
+		res = res[:len(src)]
+	} else {
+		res = append(res, make(slice, len(src)-len(res))...)
+	}
+	for i, x := range src {
+		if err := copySerializerInto(&res[i], x); err != nil {
+			return err
+		}
+	}
+	*dst = res
+	return nil
+}
+
+func copyMapInto(dst *Map, src Map) error {
+	if src == nil {
+		*dst = nil
+		return nil
+	} else if *dst == nil {
+		*dst = make(Map, len(src))
+	}
+	res := *dst
+	for key := range res {
+		if _, ok := src[key]; !ok {
+			delete(res, key)
+		}
+	}
+	for key, value := range src {
+		old := res[key]
+		if err := copySerializerInto(&old, value); err != nil {
+			return essentials.AddCtx("map key "+key, err)
+		}
+		res[key] = old
+	}
+	return nil
+}
+
+// copySerializerInto copies src into *dst, reusing the
+// old value if it has the same type as src.
+func copySerializerInto(dst *Serializer, src Serializer) error {
+	if src == nil || *dst == nil || reflect.TypeOf(*dst) != reflect.TypeOf(src) {
+		copied, err := copySerializer(src)
+		*dst = copied
+		return err
+	}
+	ptr := reflect.New(reflect.TypeOf(src))
+	ptr.Elem().Set(reflect.ValueOf(*dst))
+	if err := CopyInto(ptr.Interface(), src); err != nil {
+		return err
+	}
+	*dst = ptr.Elem().Interface().(Serializer)
+	return nil
+}
+
+// copySerializer copies a Serializer with Copy.
+func copySerializer(obj Serializer) (Serializer, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	copied, err := Copy(obj)
+	if err != nil {
+		return nil, err
+	}
+	res, ok := copied.(Serializer)
+	if !ok {
+		return nil, fmt.Errorf("copy of %T produced %T", obj, copied)
 	}
+	return res, nil
 }
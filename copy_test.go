@@ -0,0 +1,191 @@
+package serializer
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type copyTestNode struct {
+	Name     string
+	Children []*copyTestNode
+	Parent   *copyTestNode
+	Values   map[string]Serializer
+	Any      interface{}
+
+	hidden []int
+	tensor *Tensor
+}
+
+func TestCopyReflection(t *testing.T) {
+	tensor, _ := TensorFromFloat64Slice(Float64Slice{1, 2})
+	root := &copyTestNode{Name: "root", hidden: []int{1, 2}, tensor: tensor}
+	child := &copyTestNode{Name: "child", Parent: root, Any: [2]string{"a", "b"}}
+	root.Children = []*copyTestNode{child, child}
+	root.Values = map[string]Serializer{"x": Int(3), "t": tensor}
+	root.Any = root.Values
+
+	copied, err := Copy(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := copied.(*copyTestNode)
+	if c == root || c.Children[0] == child {
+		t.Fatal("pointers were not copied")
+	}
+	if c.Children[0] != c.Children[1] || c.Children[0].Parent != c {
+		t.Error("shared references were not preserved")
+	}
+	if reflect.ValueOf(c.Any).Pointer() != reflect.ValueOf(c.Values).Pointer() {
+		t.Error("shared map was not preserved")
+	}
+	if c.Values["t"] == Serializer(tensor) || c.tensor != c.Values["t"] {
+		t.Error("tensor was not copied once")
+	}
+	if !reflect.DeepEqual(c.tensor, tensor) || c.Values["x"] != Int(3) ||
+		c.Children[0].Any != [2]string{"a", "b"} {
+		t.Errorf("unexpected copy: %+v", c)
+	}
+	c.hidden[0] = 5
+	if !reflect.DeepEqual(c.hidden, []int{5, 2}) || root.hidden[0] != 1 {
+		t.Error("unexported slice was not copied")
+	}
+
+	if _, err := Copy(struct{ C chan int }{make(chan int)}); err == nil {
+		t.Error("expected error for channel")
+	}
+	if res, err := Copy(struct{ F func() }{}); err != nil || res.(struct{ F func() }).F != nil {
+		t.Errorf("unexpected result for nil func: %v %v", res, err)
+	}
+	if res, err := Copy(nil); err != nil || res != nil {
+		t.Errorf("unexpected result for nil: %v %v", res, err)
+	}
+}
+
+func TestCopyBuiltins(t *testing.T) {
+	tensor, _ := TensorFromFloat32Slice(Float32Slice{1, 2, 3, 4}, 2, 2)
+	objects := []Serializer{
+		Int(3), String("hi"), Float64Slice{1, 2}, Float64Slice{}, Bytes(nil),
+		Uint16Slice{7}, Complex128Slice{1i}, StringSlice{"a"}, BoolSlice{true},
+		IntSlice2D{{1}, {2, 3}}, Time(time.Unix(5, 0)), Duration(time.Second),
+		(*BigInt)(big.NewInt(-5)), (*BigFloat)(big.NewFloat(1.5)),
+		(*BigRat)(big.NewRat(1, 3)), tensor,
+		slice{Float64Slice{1}, tensor, nil}, Map{"x": IntSlice{1}, "y": nil},
+	}
+	for _, obj := range objects {
+		if _, ok := obj.(Copier); !ok {
+			t.Errorf("%T does not implement Copier", obj)
+			continue
+		}
+		copied, err := Copy(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(copied, obj) {
+			t.Errorf("expected %v but got %v", obj, copied)
+		}
+	}
+
+	s := Float64Slice{1, 2}
+	c, _ := Copy(s)
+	c.(Float64Slice)[0] = 3
+	if s[0] != 1 {
+		t.Error("slice was not copied")
+	}
+
+	nils := slice{(*BigInt)(nil), (*BigFloat)(nil), (*BigRat)(nil), (*Tensor)(nil)}
+	if copied, err := Copy(nils); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(copied, nils) {
+		t.Errorf("expected %v but got %v", nils, copied)
+	}
+
+	n := big.NewInt(3)
+	cn, _ := Copy((*BigInt)(n))
+	(*big.Int)(cn.(*BigInt)).SetInt64(4)
+	if n.Int64() != 3 {
+		t.Error("big.Int was not copied")
+	}
+
+	view := tensor.Transpose()
+	ct, _ := Copy(view)
+	if !ct.(*Tensor).IsContiguous() ||
+		!reflect.DeepEqual(ct.(*Tensor).Float32Slice(), Float32Slice{1, 3, 2, 4}) {
+		t.Errorf("unexpected tensor copy: %v", ct)
+	}
+}
+
+func TestCopyInto(t *testing.T) {
+	dst := make(Float64Slice, 1, 10)
+	buf := &dst[0]
+	if err := CopyInto(&dst, Float64Slice{4, 5, 6}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, Float64Slice{4, 5, 6}) || &dst[0] != buf {
+		t.Errorf("unexpected result: %v", dst)
+	}
+
+	rows := [][]int{make([]int, 0, 4)}
+	row := &rows[0][:1][0]
+	if err := CopyInto(&rows, [][]int{{1, 2}, {3}}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rows, [][]int{{1, 2}, {3}}) || &rows[0][0] != row {
+		t.Errorf("unexpected rows: %v", rows)
+	}
+
+	inner := make(Float32Slice, 3)
+	dstSlice := slice{inner, Int(1)}
+	if err := CopyInto(&dstSlice, slice{Float32Slice{7}, String("x")}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dstSlice, slice{Float32Slice{7}, String("x")}) ||
+		&dstSlice[0].(Float32Slice)[0] != &inner[0] {
+		t.Errorf("unexpected slice: %v", dstSlice)
+	}
+
+	staleInner := Float32Slice{9}
+	dstSlice = slice{staleInner}[:0]
+	if err := CopyInto(&dstSlice, slice{Float32Slice{7}}); err != nil {
+		t.Fatal(err)
+	}
+	staleRow := []int{9}
+	rows = [][]int{staleRow}[:0]
+	if err := CopyInto(&rows, [][]int{{1}}); err != nil {
+		t.Fatal(err)
+	}
+	if staleInner[0] != 9 || staleRow[0] != 9 {
+		t.Error("elements beyond the length of dst were reused")
+	}
+
+	dstMap := Map{"a": Float64Slice{0, 0}, "b": Int(1)}
+	if err := CopyInto(&dstMap, Map{"a": Float64Slice{3}}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dstMap, Map{"a": Float64Slice{3}}) {
+		t.Errorf("unexpected map: %v", dstMap)
+	}
+
+	src, _ := TensorFromFloat64Slice(Float64Slice{1, 2})
+	dstTensor := NewTensor(DTypeFloat64, 4)
+	data := &dstTensor.Data[0]
+	if err := CopyInto(&dstTensor, src); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dstTensor, src) || &dstTensor.Data[0] != data {
+		t.Errorf("unexpected tensor: %v", dstTensor)
+	}
+
+	if err := CopyInto(&dstTensor, (*Tensor)(nil)); err != nil || dstTensor != nil {
+		t.Errorf("unexpected nil tensor copy: %v %v", dstTensor, err)
+	}
+
+	var node *copyTestNode
+	if err := CopyInto(&node, &copyTestNode{Name: "x"}); err != nil || node.Name != "x" {
+		t.Errorf("unexpected node: %v %v", node, err)
+	}
+	if err := CopyInto(&dst, IntSlice{1}); err == nil {
+		t.Error("expected type error")
+	}
+}
@@ -1,31 +1,431 @@
 package serializer
 
 import (
+	"errors"
 	"reflect"
 	"sync"
+
+	"github.com/unixpickle/essentials"
 )
 
-var deserializersLock sync.RWMutex
-var deserializers = map[string]Deserializer{}
+// DefaultRegistry is the Registry used by the global
+// functions of this package, such as GetDeserializer and
+// DeserializeWithType.
+var DefaultRegistry = NewRegistry()
 
 // A Deserializer is a function which can deserialize
 // a certain type of object.
+//
+// A Deserializer which decodes nested objects with the
+// global functions (such as DeserializeSlice) always uses
+// the DefaultRegistry for them, and the nesting depth
+// starts over at zero.
+// To decode nested objects with the Registry that invoked
+// the deserializer, use a ContextDeserializer instead.
 type Deserializer func(d []byte) (Serializer, error)
 
+// A ContextDeserializer is like a Deserializer, but it is
+// passed the DecodeContext of the object being decoded.
+//
+// Nested objects should be decoded with the methods of
+// ctx, so that they are looked up in the same Registry
+// and count towards the same DecodeLimits.
+type ContextDeserializer func(d []byte, ctx *DecodeContext) (Serializer, error)
+
+// A DecodeContext describes where an object is being
+// decoded, namely the Registry in use and the nesting
+// depth of the object.
+type DecodeContext struct {
+	registry *Registry
+	depth    int
+}
+
+// Registry returns the Registry used to look up type IDs.
+func (c *DecodeContext) Registry() *Registry {
+	return c.registry
+}
+
+// DeserializeWithType decodes a nested object which was
+// encoded with SerializeWithType.
+func (c *DecodeContext) DeserializeWithType(d []byte) (Serializer, error) {
+	return c.registry.deserializeWithType(d, c.depth+1)
+}
+
+// DeserializeSlice decodes nested objects which were
+// encoded with SerializeSlice.
+func (c *DecodeContext) DeserializeSlice(d []byte) ([]Serializer, error) {
+	return c.registry.deserializeSlice(d, c.depth+1)
+}
+
+// DeserializeAny decodes nested objects which were
+// encoded with SerializeAny.
+func (c *DecodeContext) DeserializeAny(d []byte, out ...interface{}) error {
+	return c.registry.deserializeAny(d, c.depth+1, out...)
+}
+
+// A Migration upgrades the serialized data of an old type
+// ID to the data format of a newer type ID.
+type Migration func(d []byte) ([]byte, error)
+
+type migration struct {
+	ToID string
+	Func Migration
+}
+
+// An Unwrapper decodes the data of a wrapper type (such
+// as Compressed) into the SerializeWithType encoding of
+// the object it wraps.
+type Unwrapper func(d []byte) ([]byte, error)
+
+// A ContextUnwrapper is like an Unwrapper, but it is
+// passed the DecodeContext of the wrapper, e.g. to apply
+// the DecodeLimits while decompressing.
+type ContextUnwrapper func(d []byte, ctx *DecodeContext) ([]byte, error)
+
+// A Registry is a table which maps type IDs to
+// Deserializers and ContextDeserializers.
+//
+// Separate Registries can be used to isolate tests or to
+// map the same type ID differently in two subsystems.
+//
+// All methods of a Registry are safe to call
+// concurrently.
+type Registry struct {
+	lock          sync.RWMutex
+	deserializers map[string]ContextDeserializer
+	aliases       map[string]string
+	migrations    map[string]migration
+	unwrappers    map[string]ContextUnwrapper
+	graph         map[string]GraphDeserializer
+	zeroCopy      bool
+	limits        DecodeLimits
+
+	hitsLock  sync.Mutex
+	aliasHits map[string]string
+}
+
+// NewRegistry creates an empty Registry.
+//
+// To create a Registry which includes the built-in types
+// and everything else registered globally, use
+// DefaultRegistry.Clone().
+func NewRegistry() *Registry {
+	return &Registry{
+		deserializers: map[string]ContextDeserializer{},
+		aliases:       map[string]string{},
+		migrations:    map[string]migration{},
+		unwrappers:    map[string]ContextUnwrapper{},
+		graph:         map[string]GraphDeserializer{},
+		aliasHits:     map[string]string{},
+	}
+}
+
+// Clone creates a copy of the Registry.
+// Changes to the copy do not affect the original, and
+// vice versa.
+func (r *Registry) Clone() *Registry {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	res := NewRegistry()
+	for typeID, d := range r.deserializers {
+		res.deserializers[typeID] = d
+	}
+	for alias, typeID := range r.aliases {
+		res.aliases[alias] = typeID
+	}
+	for typeID, m := range r.migrations {
+		res.migrations[typeID] = m
+	}
+	for typeID, u := range r.unwrappers {
+		res.unwrappers[typeID] = u
+	}
+	for typeID, g := range r.graph {
+		res.graph[typeID] = g
+	}
+	res.zeroCopy = r.zeroCopy
+	res.limits = r.limits
+	return res
+}
+
+// Get returns the Deserializer that is currently
+// registered for the given type ID.
+// This returns nil if no Deserializer is registered.
+//
+// If no Deserializer is registered for the type ID itself
+// but the type ID is an alias, the alias is resolved and
+// recorded in the Registry's alias hits.
+//
+// If the type ID has a ContextDeserializer, the result
+// calls it with a new DecodeContext for r.
+func (r *Registry) Get(typeID string) Deserializer {
+	d := r.lookup(typeID)
+	if d == nil {
+		return nil
+	}
+	return func(data []byte) (Serializer, error) {
+		return d(data, &DecodeContext{registry: r})
+	}
+}
+
+// lookup is like Get, but it returns the registered
+// ContextDeserializer.
+func (r *Registry) lookup(typeID string) ContextDeserializer {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if d, ok := r.deserializers[typeID]; ok {
+		return d
+	}
+	resolved := typeID
+	for i := 0; i < len(r.aliases); i++ {
+		next, ok := r.aliases[resolved]
+		if !ok {
+			return nil
+		}
+		resolved = next
+		if d, ok := r.deserializers[resolved]; ok {
+			r.hitsLock.Lock()
+			r.aliasHits[typeID] = resolved
+			r.hitsLock.Unlock()
+			return d
+		}
+	}
+	return nil
+}
+
+// RegisterAlias makes a legacy type ID resolve to the
+// Deserializer for another type ID.
+// This makes it possible to rename a type (e.g. by moving
+// it to a different package) without breaking old data.
+//
+// Aliases may point to other aliases, in which case they
+// are resolved recursively.
+//
+// This panics if the alias is already in use, either as
+// an alias or as a type ID.
+func (r *Registry) RegisterAlias(alias, typeID string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.deserializers[alias]; ok {
+		panic("type ID already in use: " + alias)
+	}
+	if _, ok := r.aliases[alias]; ok {
+		panic("alias already in use: " + alias)
+	}
+	r.aliases[alias] = typeID
+}
+
+// RegisterMigration registers a Migration which converts
+// data with the type ID fromID into data with the type ID
+// toID (e.g. from "MyType@v1" to "MyType@v2").
+//
+// When DeserializeWithType encounters a type ID with no
+// registered Deserializer, it applies migrations until it
+// reaches a type ID that can be deserialized.
+// Thus, migrations can be chained across several
+// versions.
+//
+// This panics if a migration from fromID is already
+// registered.
+func (r *Registry) RegisterMigration(fromID, toID string, m Migration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.migrations[fromID]; ok {
+		panic("migration already registered: " + fromID)
+	}
+	r.migrations[fromID] = migration{ToID: toID, Func: m}
+}
+
+// RegisterUnwrapper registers an Unwrapper for a wrapper
+// type ID.
+//
+// When DeserializeWithType encounters the type ID, it
+// uses the Unwrapper to decode the data and then
+// deserializes the wrapped object, which is returned in
+// place of the wrapper.
+//
+// This panics if the type ID already has an Unwrapper.
+func (r *Registry) RegisterUnwrapper(typeID string, u Unwrapper) {
+	r.RegisterContextUnwrapper(typeID, func(d []byte, ctx *DecodeContext) ([]byte, error) {
+		return u(d)
+	})
+}
+
+// RegisterContextUnwrapper is like RegisterUnwrapper, but
+// it takes a ContextUnwrapper.
+func (r *Registry) RegisterContextUnwrapper(typeID string, u ContextUnwrapper) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.unwrappers[typeID]; ok {
+		panic("unwrapper already registered: " + typeID)
+	}
+	r.unwrappers[typeID] = u
+}
+
+// UpdateUnwrapper is like RegisterUnwrapper, but it
+// replaces any existing Unwrapper for the type ID rather
+// than panicking.
+//
+// Passing a nil Unwrapper removes the type ID's Unwrapper,
+// allowing RegisterUnwrapper to be called again.
+func (r *Registry) UpdateUnwrapper(typeID string, u Unwrapper) {
+	if u == nil {
+		r.UpdateContextUnwrapper(typeID, nil)
+		return
+	}
+	r.UpdateContextUnwrapper(typeID, func(d []byte, ctx *DecodeContext) ([]byte, error) {
+		return u(d)
+	})
+}
+
+// UpdateContextUnwrapper is like UpdateUnwrapper, but it
+// takes a ContextUnwrapper.
+func (r *Registry) UpdateContextUnwrapper(typeID string, u ContextUnwrapper) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if u == nil {
+		delete(r.unwrappers, typeID)
+	} else {
+		r.unwrappers[typeID] = u
+	}
+}
+
+// GetUnwrapper returns the Unwrapper registered for the
+// type ID, or nil if there is none.
+//
+// If the type ID has a ContextUnwrapper, the result calls
+// it with a new DecodeContext for r.
+func (r *Registry) GetUnwrapper(typeID string) Unwrapper {
+	u := r.getContextUnwrapper(typeID)
+	if u == nil {
+		return nil
+	}
+	return func(d []byte) ([]byte, error) {
+		return u(d, &DecodeContext{registry: r})
+	}
+}
+
+func (r *Registry) getContextUnwrapper(typeID string) ContextUnwrapper {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.unwrappers[typeID]
+}
+
+// resolve finds the Deserializer for a type ID, applying
+// migrations to the data as needed.
+func (r *Registry) resolve(typeID string, d []byte) (ContextDeserializer, []byte, error) {
+	for i := 0; ; i++ {
+		if deserializer := r.lookup(typeID); deserializer != nil {
+			return deserializer, d, nil
+		}
+
+		r.lock.RLock()
+		m, ok := r.migrations[typeID]
+		numMigrations := len(r.migrations)
+		r.lock.RUnlock()
+
+		if !ok {
+			return nil, nil, errors.New("unregistered type ID: " + typeID)
+		} else if i >= numMigrations {
+			return nil, nil, errors.New("migration cycle: " + typeID)
+		}
+		var err error
+		d, err = m.Func(d)
+		if err != nil {
+			return nil, nil, essentials.AddCtx("migrate "+typeID+" to "+m.ToID, err)
+		}
+		typeID = m.ToID
+	}
+}
+
+// AliasHits returns the aliases that have been resolved
+// by Get since the Registry was created or since the last
+// call to ResetAliasHits.
+//
+// The result maps each legacy type ID to the type ID it
+// resolved to.
+// This can be used to find out which old files should be
+// rewritten; to track a single load in isolation, use a
+// Clone of the Registry.
+func (r *Registry) AliasHits() map[string]string {
+	r.hitsLock.Lock()
+	defer r.hitsLock.Unlock()
+	res := map[string]string{}
+	for alias, typeID := range r.aliasHits {
+		res[alias] = typeID
+	}
+	return res
+}
+
+// ResetAliasHits clears the list of aliases returned by
+// AliasHits.
+func (r *Registry) ResetAliasHits() {
+	r.hitsLock.Lock()
+	defer r.hitsLock.Unlock()
+	r.aliasHits = map[string]string{}
+}
+
+// Update adds or changes the Deserializer for a given
+// type ID.
+//
+// Passing a nil Deserializer will completely remove the
+// type ID from the table, allowing Register to be called
+// again.
+func (r *Registry) Update(typeID string, d Deserializer) {
+	r.UpdateContext(typeID, contextDeserializer(d))
+}
+
+// Register is like Update, but it panics if the type ID
+// is already in use.
+func (r *Registry) Register(typeID string, d Deserializer) {
+	r.RegisterContext(typeID, contextDeserializer(d))
+}
+
+// UpdateContext is like Update, but it takes a
+// ContextDeserializer.
+//
+// Passing a nil ContextDeserializer will completely remove
+// the type ID from the table.
+func (r *Registry) UpdateContext(typeID string, d ContextDeserializer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if d == nil {
+		delete(r.deserializers, typeID)
+	} else {
+		r.deserializers[typeID] = d
+	}
+}
+
+// RegisterContext is like UpdateContext, but it panics if
+// the type ID is already in use.
+func (r *Registry) RegisterContext(typeID string, d ContextDeserializer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.deserializers[typeID]; ok {
+		panic("type ID already in use: " + typeID)
+	}
+	r.deserializers[typeID] = d
+}
+
+// RegisterTyped is like Register, but it converts a
+// typed function into a Deserializer.
+// See RegisterTypedDeserializer for details.
+func (r *Registry) RegisterTyped(typeID string, f interface{}) {
+	r.Register(typeID, typedDeserializer(f))
+}
+
 // GetDeserializer returns the Deserializer that is
-// currently registered for the given type ID.
+// currently registered for the given type ID in the
+// DefaultRegistry.
 // This returns nil if no Deserializer is registered.
 //
 // All routines which manage the deserializer table
 // are safe to call concurrently.
 func GetDeserializer(typeID string) Deserializer {
-	deserializersLock.RLock()
-	defer deserializersLock.RUnlock()
-	return deserializers[typeID]
+	return DefaultRegistry.Get(typeID)
 }
 
 // UpdateDeserializer adds or changes the Deserializer
-// for a given type ID.
+// for a given type ID in the DefaultRegistry.
 //
 // Passing a nil Deserializer will completely remove
 // the type ID from the table, allowing
@@ -34,13 +434,7 @@ func GetDeserializer(typeID string) Deserializer {
 // All routines which manage the deserializer table
 // are safe to call concurrently.
 func UpdateDeserializer(typeID string, d Deserializer) {
-	deserializersLock.Lock()
-	defer deserializersLock.Unlock()
-	if d == nil {
-		delete(deserializers, typeID)
-	} else {
-		deserializers[typeID] = d
-	}
+	DefaultRegistry.Update(typeID, d)
 }
 
 // RegisterDeserializer is like UpdateDeserializer,
@@ -49,12 +443,63 @@ func UpdateDeserializer(typeID string, d Deserializer) {
 // All routines which manage the deserializer table
 // are safe to call concurrently.
 func RegisterDeserializer(typeID string, d Deserializer) {
-	deserializersLock.Lock()
-	defer deserializersLock.Unlock()
-	if _, ok := deserializers[typeID]; ok {
-		panic("type ID already in use: " + typeID)
-	}
-	deserializers[typeID] = d
+	DefaultRegistry.Register(typeID, d)
+}
+
+// UpdateContextDeserializer adds or changes the
+// ContextDeserializer for a given type ID in the
+// DefaultRegistry.
+// See Registry.UpdateContext for details.
+func UpdateContextDeserializer(typeID string, d ContextDeserializer) {
+	DefaultRegistry.UpdateContext(typeID, d)
+}
+
+// RegisterContextDeserializer is like
+// UpdateContextDeserializer, but it panics if the type ID
+// is already in use.
+func RegisterContextDeserializer(typeID string, d ContextDeserializer) {
+	DefaultRegistry.RegisterContext(typeID, d)
+}
+
+// RegisterAlias registers an alias for a type ID in the
+// DefaultRegistry.
+// See Registry.RegisterAlias for details.
+func RegisterAlias(alias, typeID string) {
+	DefaultRegistry.RegisterAlias(alias, typeID)
+}
+
+// RegisterMigration registers a Migration from one type ID
+// to another in the DefaultRegistry.
+// See Registry.RegisterMigration for details.
+func RegisterMigration(fromID, toID string, m Migration) {
+	DefaultRegistry.RegisterMigration(fromID, toID, m)
+}
+
+// RegisterUnwrapper registers an Unwrapper for a wrapper
+// type ID in the DefaultRegistry.
+// See Registry.RegisterUnwrapper for details.
+func RegisterUnwrapper(typeID string, u Unwrapper) {
+	DefaultRegistry.RegisterUnwrapper(typeID, u)
+}
+
+// RegisterContextUnwrapper registers a ContextUnwrapper
+// for a wrapper type ID in the DefaultRegistry.
+// See Registry.RegisterUnwrapper for details.
+func RegisterContextUnwrapper(typeID string, u ContextUnwrapper) {
+	DefaultRegistry.RegisterContextUnwrapper(typeID, u)
+}
+
+// UpdateUnwrapper adds, changes, or removes the Unwrapper
+// for a wrapper type ID in the DefaultRegistry.
+// See Registry.UpdateUnwrapper for details.
+func UpdateUnwrapper(typeID string, u Unwrapper) {
+	DefaultRegistry.UpdateUnwrapper(typeID, u)
+}
+
+// UpdateContextUnwrapper is like UpdateUnwrapper, but it
+// takes a ContextUnwrapper.
+func UpdateContextUnwrapper(typeID string, u ContextUnwrapper) {
+	DefaultRegistry.UpdateContextUnwrapper(typeID, u)
 }
 
 // RegisterTypedDeserializer is like RegisterDeserializer,
@@ -75,13 +520,28 @@ func RegisterDeserializer(typeID string, d Deserializer) {
 // The job of RegisterTypedDeserializer is to use a method
 // like DeserializeMyType as a deserializer.
 func RegisterTypedDeserializer(typeID string, f interface{}) {
+	DefaultRegistry.RegisterTyped(typeID, f)
+}
+
+// contextDeserializer converts a Deserializer into a
+// ContextDeserializer which ignores the context.
+func contextDeserializer(d Deserializer) ContextDeserializer {
+	if d == nil {
+		return nil
+	}
+	return func(data []byte, ctx *DecodeContext) (Serializer, error) {
+		return d(data)
+	}
+}
+
+func typedDeserializer(f interface{}) Deserializer {
 	val := reflect.ValueOf(f)
-	RegisterDeserializer(typeID, func(d []byte) (Serializer, error) {
+	return func(d []byte) (Serializer, error) {
 		res := val.Call([]reflect.Value{reflect.ValueOf(d)})
 		if res[1].IsNil() {
 			return res[0].Interface().(Serializer), nil
 		} else {
 			return nil, res[1].Interface().(error)
 		}
-	})
+	}
 }
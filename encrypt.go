@@ -0,0 +1,208 @@
+package serializer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/unixpickle/essentials"
+)
+
+const encryptedTypeID = "encrypted/aes-gcm"
+
+var ErrDecryption = errors.New("decryption failed")
+
+// DefaultKeyring is the Keyring used to decrypt Encrypted
+// objects in the DefaultRegistry.
+var DefaultKeyring = NewKeyring()
+
+func init() {
+	RegisterUnwrapper(encryptedTypeID, DefaultKeyring.Unwrap)
+}
+
+// Encrypted is a Serializer which encrypts another
+// Serializer using AES-GCM.
+//
+// The key ID is stored alongside the ciphertext, allowing
+// keys to be rotated: the Keyring used for decryption may
+// hold both old and new keys.
+// The key ID is authenticated but not encrypted.
+//
+// When deserialized with DeserializeWithType, the data is
+// decrypted using DefaultKeyring and the original Value is
+// returned rather than an Encrypted.
+// To decrypt with a different Keyring, use Keyring.Open,
+// or replace the Unwrapper in a Registry:
+//
+//     r := serializer.DefaultRegistry.Clone()
+//     r.UpdateUnwrapper((&serializer.Encrypted{}).SerializerType(),
+//         keyring.Unwrap)
+type Encrypted struct {
+	KeyID string
+
+	// Key is an AES key (16, 24, or 32 bytes).
+	Key []byte
+
+	Value Serializer
+}
+
+// Serialize serializes the object.
+func (e *Encrypted) Serialize() (data []byte, err error) {
+	defer essentials.AddCtxTo("serialize encrypted", &err)
+
+	block, err := aes.NewCipher(e.Key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := SerializeWithType(e.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 4+len(e.KeyID))
+	helperByteOrder.PutUint32(header, uint32(len(e.KeyID)))
+	copy(header[4:], e.KeyID)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	res := append(header, nonce...)
+	return gcm.Seal(res, nonce, plaintext, header), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Encrypted.
+func (e *Encrypted) SerializerType() string {
+	return encryptedTypeID
+}
+
+// Seal encrypts a Serializer with the given key, producing
+// data that can be decoded with DeserializeWithType or
+// Keyring.Open.
+//
+// It is equivalent to SerializeWithType on an Encrypted.
+func Seal(keyID string, key []byte, s Serializer) ([]byte, error) {
+	return SerializeWithType(&Encrypted{KeyID: keyID, Key: key, Value: s})
+}
+
+// A Keyring stores AES keys by key ID for decrypting
+// Encrypted objects.
+//
+// All methods of a Keyring are safe to call concurrently.
+type Keyring struct {
+	lock sync.RWMutex
+	keys map[string][]byte
+}
+
+// NewKeyring creates an empty Keyring.
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string][]byte{}}
+}
+
+// AddKey adds or replaces a key.
+// The key must be a valid AES key.
+func (k *Keyring) AddKey(keyID string, key []byte) error {
+	if _, err := aes.NewCipher(key); err != nil {
+		return essentials.AddCtx("add key", err)
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.keys[keyID] = append([]byte{}, key...)
+	return nil
+}
+
+// RemoveKey removes a key, if it exists.
+func (k *Keyring) RemoveKey(keyID string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	delete(k.keys, keyID)
+}
+
+// Key returns a copy of the key for a key ID, or nil if
+// the key ID is not present.
+func (k *Keyring) Key(keyID string) []byte {
+	key := k.key(keyID)
+	if key == nil {
+		return nil
+	}
+	return append([]byte{}, key...)
+}
+
+func (k *Keyring) key(keyID string) []byte {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	return k.keys[keyID]
+}
+
+// Open decrypts data produced by Seal (or by
+// SerializeWithType on an Encrypted) and deserializes the
+// inner object with the Registry r.
+// If r is nil, DefaultRegistry is used.
+//
+// If the data was tampered with or the key is wrong, this
+// fails with ErrDecryption before the inner object's
+// Deserializer is called.
+func (k *Keyring) Open(d []byte, r *Registry) (obj Serializer, err error) {
+	defer essentials.AddCtxTo("open", &err)
+	typeID, data, err := SplitWithType(d)
+	if err != nil {
+		return nil, err
+	}
+	if typeID != encryptedTypeID {
+		return nil, errors.New("unexpected type ID: " + typeID)
+	}
+	inner, err := k.Unwrap(data)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		r = DefaultRegistry
+	}
+	return r.DeserializeWithType(inner)
+}
+
+// Unwrap is an Unwrapper for Encrypted objects.
+// It decrypts the data of an Encrypted, producing the
+// SerializeWithType encoding of the inner object.
+func (k *Keyring) Unwrap(d []byte) (res []byte, err error) {
+	defer essentials.AddCtxTo("decrypt", &err)
+
+	// The key ID is framed like a type ID.
+	keyID, rest, err := SplitWithType(d)
+	if err != nil {
+		return nil, err
+	}
+	header := d[:len(d)-len(rest)]
+
+	key := k.key(keyID)
+	if key == nil {
+		return nil, errors.New("unknown key ID: " + keyID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrBufferUnderflow
+	}
+	nonce := rest[:gcm.NonceSize()]
+	res, err = gcm.Open(nil, nonce, rest[len(nonce):], header)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return res, nil
+}
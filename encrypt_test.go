@@ -0,0 +1,105 @@
+package serializer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncrypted(t *testing.T) {
+	oldKey := []byte("0123456789abcdef")
+	newKey := []byte("0123456789abcdef0123456789abcdef")
+
+	keys := NewKeyring()
+	if err := keys.AddKey("old", oldKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddKey("new", newKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddKey("bad", []byte("short")); err == nil {
+		t.Error("expected error for invalid key")
+	}
+
+	value := Float32Slice{1, 2, 3}
+	for keyID, key := range map[string][]byte{"old": oldKey, "new": newKey} {
+		data, err := Seal(keyID, key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj, err := keys.Open(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(obj, value) {
+			t.Errorf("expected %v but got %v", value, obj)
+		}
+
+		for i := 4 + len(encryptedTypeID); i < len(data); i++ {
+			corrupted := append([]byte{}, data...)
+			corrupted[i] ^= 1
+			if _, err := keys.Open(corrupted, nil); err == nil {
+				t.Errorf("corruption at byte %d not detected", i)
+			}
+		}
+	}
+
+	data, err := Seal("new", newKey, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.Key("new")[0] ^= 1
+	if _, err := keys.Open(data, nil); err != nil {
+		t.Error("internal key was modified:", err)
+	}
+	if _, err := keys.Open(data, NewRegistry()); err == nil {
+		t.Error("expected error for empty registry")
+	}
+
+	r := DefaultRegistry.Clone()
+	r.UpdateUnwrapper(encryptedTypeID, keys.Unwrap)
+	if obj, err := r.DeserializeWithType(data); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(obj, value) {
+		t.Errorf("expected %v but got %v", value, obj)
+	}
+	if _, err := DeserializeWithType(data); err == nil {
+		t.Error("DefaultRegistry was affected")
+	}
+	r.UpdateUnwrapper(encryptedTypeID, nil)
+	if r.GetUnwrapper(encryptedTypeID) != nil {
+		t.Error("unwrapper was not removed")
+	}
+
+	header := []byte{0xff, 0xff, 0xff, 0xff, 1, 2, 3, 4}
+	if _, err := keys.Unwrap(header); err == nil {
+		t.Error("expected error for invalid key ID size")
+	}
+	if _, err := keys.Open(header, nil); err == nil {
+		t.Error("expected error for invalid type ID size")
+	}
+
+	keys.RemoveKey("new")
+	if _, err := keys.Open(data, nil); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
+
+func TestEncryptedDefaultKeyring(t *testing.T) {
+	key := []byte("fedcba9876543210")
+	if err := DefaultKeyring.AddKey("test", key); err != nil {
+		t.Fatal(err)
+	}
+	defer DefaultKeyring.RemoveKey("test")
+
+	data, err := SerializeAny(&Encrypted{KeyID: "test", Key: key, Value: String("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err := DeserializeAny(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "secret" {
+		t.Errorf("unexpected result: %s", s)
+	}
+}
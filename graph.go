@@ -0,0 +1,340 @@
+package serializer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	RegisterGraphDeserializer(slice(nil).SerializerType(), deserializeGraphSlice)
+	RegisterGraphDeserializer(Map(nil).SerializerType(), deserializeGraphMap)
+}
+
+// A GraphSerializer is a Serializer which can refer to
+// other objects in an object graph.
+//
+// In graph mode (see SerializeGraph), the object is
+// encoded with SerializeGraph instead of Serialize and
+// decoded with the GraphDeserializer registered for its
+// type ID.
+type GraphSerializer interface {
+	Serializer
+
+	// SerializeGraph serializes the object, using e.Ref to
+	// encode references to other objects.
+	SerializeGraph(e *GraphEncoder) ([]byte, error)
+}
+
+// A GraphDeserializer decodes the output of a
+// GraphSerializer's SerializeGraph method.
+//
+// References should be decoded with d.Resolve.
+type GraphDeserializer func(data []byte, d *GraphDecoder) (Serializer, error)
+
+// A GraphEncoder assigns IDs to the objects in a graph.
+type GraphEncoder struct {
+	ids     map[graphKey]uint64
+	objects []Serializer
+}
+
+type graphKey struct {
+	Type    reflect.Type
+	Pointer uintptr
+	Len     int
+}
+
+// Ref returns the ID of an object, adding the object to
+// the graph if necessary.
+//
+// Pointers and non-empty maps and slices are identified
+// by their addresses, so passing the same pointer twice
+// yields the same ID.
+// Other objects are added to the graph every time.
+//
+// Nil objects, including nil pointers, have ID 0.
+func (g *GraphEncoder) Ref(obj Serializer) uint64 {
+	if obj == nil {
+		return 0
+	}
+	if val := reflect.ValueOf(obj); val.Kind() == reflect.Ptr && val.IsNil() {
+		return 0
+	}
+	key, ok := identityKey(reflect.ValueOf(obj))
+	if ok {
+		if id, ok := g.ids[key]; ok {
+			return id
+		}
+	}
+	g.objects = append(g.objects, obj)
+	id := uint64(len(g.objects))
+	if ok {
+		g.ids[key] = id
+	}
+	return id
+}
+
+// identityKey returns a key which identifies a pointer or
+// a non-empty map or slice by its address.
+func identityKey(val reflect.Value) (graphKey, bool) {
+	switch val.Kind() {
+	case reflect.Ptr:
+		return graphKey{Type: val.Type(), Pointer: val.Pointer()}, true
+	case reflect.Map, reflect.Slice:
+		if val.Len() == 0 {
+			return graphKey{}, false
+		}
+		return graphKey{Type: val.Type(), Pointer: val.Pointer(), Len: val.Len()}, true
+	}
+	return graphKey{}, false
+}
+
+// SerializeGraph serializes a graph of objects, preserving
+// shared references and cycles.
+//
+// Each object reachable from the roots is encoded once.
+// GraphSerializers, []Serializer values, and Map values
+// refer to their children by ID, so a pointer that
+// appears several times in the graph is decoded as a
+// single object.
+// Other objects are encoded with SerializeWithType, so
+// the objects nested inside them are not shared.
+//
+// The result can be decoded with DeserializeGraph.
+func SerializeGraph(roots ...Serializer) (data []byte, err error) {
+	defer essentials.AddCtxTo("serialize graph", &err)
+	e := &GraphEncoder{ids: map[graphKey]uint64{}}
+	rootIDs := make(Uint64Slice, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = e.Ref(root)
+	}
+	data, err = AppendSlice(nil, []Serializer{rootIDs})
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(e.objects); i++ {
+		start := len(data)
+		data = append(data, make([]byte, 8)...)
+		obj := e.objects[i]
+		if gs, ok := obj.(GraphSerializer); ok {
+			payload, err := gs.SerializeGraph(e)
+			if err != nil {
+				return nil, essentials.AddCtx(obj.SerializerType(), err)
+			}
+			data = helperByteOrder.AppendUint32(data, uint32(len(obj.SerializerType())))
+			data = append(data, obj.SerializerType()...)
+			data = append(data, payload...)
+		} else {
+			data, err = AppendWithType(data, obj)
+			if err != nil {
+				return nil, err
+			}
+		}
+		helperByteOrder.PutUint64(data[start:], uint64(len(data)-(start+8)))
+	}
+	return data, nil
+}
+
+// DeserializeGraph decodes the output of SerializeGraph
+// using the DefaultRegistry.
+func DeserializeGraph(d []byte) ([]Serializer, error) {
+	return DefaultRegistry.DeserializeGraph(d)
+}
+
+// DeserializeGraph decodes the output of SerializeGraph
+// and returns the roots.
+//
+// Objects are decoded in two phases.
+// First, every object is created by its Deserializer or
+// GraphDeserializer.
+// Then, the callbacks passed to GraphDecoder.Resolve are
+// called, allowing objects to refer to each other.
+func (r *Registry) DeserializeGraph(d []byte) (roots []Serializer, err error) {
+	defer essentials.AddCtxTo("deserialize graph", &err)
+	records, err := SplitSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrBufferUnderflow
+	}
+	rootObj, err := r.deserializeWithType(records[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	rootIDs, ok := rootObj.(Uint64Slice)
+	if !ok {
+		return nil, fmt.Errorf("unexpected roots type: %T", rootObj)
+	}
+
+	dec := &GraphDecoder{objects: make([]Serializer, len(records)-1)}
+	for i, record := range records[1:] {
+		typeID, payload, err := SplitWithType(record)
+		if err != nil {
+			return nil, err
+		}
+		if g := r.GetGraphDeserializer(typeID); g != nil {
+			dec.objects[i], err = g(payload, dec)
+		} else {
+			dec.objects[i], err = r.deserializeWithType(record, 0)
+		}
+		if err != nil {
+			return nil, essentials.AddCtx(fmt.Sprintf("object %d", i+1), err)
+		}
+	}
+	for _, p := range dec.pending {
+		obj, err := dec.get(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.Func(obj); err != nil {
+			return nil, essentials.AddCtx(fmt.Sprintf("resolve object %d", p.ID), err)
+		}
+	}
+
+	roots = make([]Serializer, len(rootIDs))
+	for i, id := range rootIDs {
+		if roots[i], err = dec.get(id); err != nil {
+			return nil, err
+		}
+	}
+	return roots, nil
+}
+
+// A GraphDecoder tracks the objects in a graph while it
+// is being decoded.
+type GraphDecoder struct {
+	objects []Serializer
+	pending []pendingRef
+}
+
+type pendingRef struct {
+	ID   uint64
+	Func func(obj Serializer) error
+}
+
+// Resolve arranges for f to be called with the object for
+// an ID, as returned by GraphEncoder.Ref.
+//
+// The function is called after every object in the graph
+// has been created, so the object may not be fully
+// resolved yet.
+// For ID 0, f is called with nil.
+func (g *GraphDecoder) Resolve(id uint64, f func(obj Serializer) error) {
+	g.pending = append(g.pending, pendingRef{ID: id, Func: f})
+}
+
+func (g *GraphDecoder) get(id uint64) (Serializer, error) {
+	if id == 0 {
+		return nil, nil
+	} else if id > uint64(len(g.objects)) {
+		return nil, fmt.Errorf("invalid object ID: %d", id)
+	}
+	return g.objects[id-1], nil
+}
+
+// RegisterGraphDeserializer registers a GraphDeserializer
+// for a type ID.
+//
+// This panics if the type ID already has a
+// GraphDeserializer.
+func (r *Registry) RegisterGraphDeserializer(typeID string, g GraphDeserializer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.graph[typeID]; ok {
+		panic("graph deserializer already registered: " + typeID)
+	}
+	r.graph[typeID] = g
+}
+
+// GetGraphDeserializer returns the GraphDeserializer
+// registered for the type ID, or nil if there is none.
+func (r *Registry) GetGraphDeserializer(typeID string) GraphDeserializer {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.graph[typeID]
+}
+
+// RegisterGraphDeserializer registers a GraphDeserializer
+// in the DefaultRegistry.
+// See Registry.RegisterGraphDeserializer for details.
+func RegisterGraphDeserializer(typeID string, g GraphDeserializer) {
+	DefaultRegistry.RegisterGraphDeserializer(typeID, g)
+}
+
+// SerializeGraph encodes the elements by ID.
+func (s slice) SerializeGraph(e *GraphEncoder) ([]byte, error) {
+	res := make([]byte, 0, 8+8*len(s))
+	res = binary.LittleEndian.AppendUint64(res, uint64(len(s)))
+	for _, obj := range s {
+		res = binary.LittleEndian.AppendUint64(res, e.Ref(obj))
+	}
+	return res, nil
+}
+
+func deserializeGraphSlice(data []byte, d *GraphDecoder) (Serializer, error) {
+	n, data, err := readCount(data, 8)
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize graph slice", err)
+	}
+	res := make(slice, n)
+	for i := range res {
+		i := i
+		d.Resolve(binary.LittleEndian.Uint64(data[i*8:]), func(obj Serializer) error {
+			res[i] = obj
+			return nil
+		})
+	}
+	return res, nil
+}
+
+// SerializeGraph encodes the keys in sorted order and the
+// values by ID.
+func (m Map) SerializeGraph(e *GraphEncoder) ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	res := binary.LittleEndian.AppendUint64(nil, uint64(len(keys)))
+	for _, key := range keys {
+		res = binary.LittleEndian.AppendUint64(res, uint64(len(key)))
+		res = append(res, key...)
+		res = binary.LittleEndian.AppendUint64(res, e.Ref(m[key]))
+	}
+	return res, nil
+}
+
+func deserializeGraphMap(data []byte, d *GraphDecoder) (obj Serializer, err error) {
+	defer essentials.AddCtxTo("deserialize graph map", &err)
+	n, data, err := readCount(data, 16)
+	if err != nil {
+		return nil, err
+	}
+	res := make(Map, n)
+	for i := 0; i < n; i++ {
+		size, rest, err := readCount(data, 1)
+		if err != nil {
+			return nil, err
+		}
+		key := string(rest[:size])
+		if len(rest) < size+8 {
+			return nil, ErrBufferUnderflow
+		}
+		id := binary.LittleEndian.Uint64(rest[size:])
+		data = rest[size+8:]
+		if _, ok := res[key]; ok {
+			return nil, errors.New("duplicate key: " + key)
+		}
+		res[key] = nil
+		d.Resolve(id, func(obj Serializer) error {
+			res[key] = obj
+			return nil
+		})
+	}
+	return res, nil
+}
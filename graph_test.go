@@ -0,0 +1,128 @@
+package serializer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func init() {
+	RegisterTypedDeserializer((*graphNode)(nil).SerializerType(), deserializeGraphNode)
+	RegisterGraphDeserializer((*graphNode)(nil).SerializerType(),
+		func(data []byte, d *GraphDecoder) (Serializer, error) {
+			if len(data) < 8 {
+				return nil, ErrBufferUnderflow
+			}
+			node := &graphNode{Name: string(data[8:])}
+			d.Resolve(binary.LittleEndian.Uint64(data), func(obj Serializer) error {
+				if obj == nil {
+					return nil
+				}
+				next, ok := obj.(*graphNode)
+				if !ok {
+					return fmt.Errorf("unexpected next node: %T", obj)
+				}
+				node.Next = next
+				return nil
+			})
+			return node, nil
+		})
+}
+
+type graphNode struct {
+	Name string
+	Next *graphNode
+}
+
+func deserializeGraphNode(d []byte) (*graphNode, error) {
+	return &graphNode{Name: string(d)}, nil
+}
+
+func (g *graphNode) Serialize() ([]byte, error) {
+	return nil, errors.New("graph mode only")
+}
+
+func (g *graphNode) SerializeGraph(e *GraphEncoder) ([]byte, error) {
+	return append(binary.LittleEndian.AppendUint64(nil, e.Ref(g.Next)), g.Name...), nil
+}
+
+func (g *graphNode) SerializerType() string {
+	return "serializer.graphNode"
+}
+
+func TestGraphSharedReferences(t *testing.T) {
+	shared := &graphNode{Name: "shared"}
+	list := slice{shared, Int(3), shared, Map{"x": shared, "y": String("y")}, nil}
+	data, err := SerializeGraph(list, shared, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, err := DeserializeGraph(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 3 {
+		t.Fatalf("expected 3 roots but got %d", len(roots))
+	}
+	decoded := roots[0].(slice)
+	node := roots[1].(*graphNode)
+	if node.Name != "shared" || node.Next != nil {
+		t.Errorf("unexpected node: %v", node)
+	}
+	if decoded[0] != node || decoded[2] != node || decoded[3].(Map)["x"] != node {
+		t.Error("shared pointer was not preserved")
+	}
+	if decoded[1] != Int(3) || decoded[3].(Map)["y"] != String("y") || decoded[4] != nil {
+		t.Errorf("unexpected slice: %v", decoded)
+	}
+	if reflect.ValueOf(roots[2]).Pointer() != reflect.ValueOf(decoded).Pointer() {
+		t.Error("shared slice was not preserved")
+	}
+}
+
+func TestGraphCycles(t *testing.T) {
+	a := &graphNode{Name: "a"}
+	b := &graphNode{Name: "b", Next: a}
+	a.Next = b
+	self := &graphNode{Name: "self"}
+	self.Next = self
+
+	loop := make(slice, 2)
+	loop[0] = loop
+	loop[1] = a
+
+	data, err := SerializeGraph(a, self, loop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, err := DeserializeGraph(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1 := roots[0].(*graphNode)
+	if a1.Name != "a" || a1.Next.Name != "b" || a1.Next.Next != a1 {
+		t.Error("two-node cycle was not restored")
+	}
+	self1 := roots[1].(*graphNode)
+	if self1.Next != self1 {
+		t.Error("self reference was not restored")
+	}
+	loop1 := roots[2].(slice)
+	if reflect.ValueOf(loop1[0]).Pointer() != reflect.ValueOf(loop1).Pointer() ||
+		loop1[1] != a1 {
+		t.Error("slice cycle was not restored")
+	}
+}
+
+func TestGraphInvalidReference(t *testing.T) {
+	refs := []byte{1, 0, 0, 0, 0, 0, 0, 0, 7, 0, 0, 0, 0, 0, 0, 0}
+	data, _ := AppendSlice(nil, []Serializer{
+		Uint64Slice{1},
+		&rawTypedData{typeID: slice(nil).SerializerType(), data: refs},
+	})
+	if _, err := DeserializeGraph(data); err == nil {
+		t.Error("expected error for invalid reference")
+	}
+}
@@ -0,0 +1,639 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/unixpickle/essentials"
+)
+
+// jsonNode is the JSON form of a SerializeWithType value.
+//
+// Exactly one of Value and Data is used: Value holds a
+// structural representation of a known type, while Data
+// holds the raw payload (base64-encoded) of any other
+// type.
+type jsonNode struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Data  *string         `json:"data,omitempty"`
+}
+
+// A jsonCodec converts a built-in type to and from a value
+// that encoding/json can handle.
+type jsonCodec struct {
+	ToJSON   func(obj Serializer) interface{}
+	NewJSON  func() interface{}
+	FromJSON func(ptr interface{}) Serializer
+}
+
+var jsonCodecs = map[string]jsonCodec{
+	String("").SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return string(obj.(String)) },
+		NewJSON:  func() interface{} { return new(string) },
+		FromJSON: func(ptr interface{}) Serializer { return String(*ptr.(*string)) },
+	},
+	Bytes(nil).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return []byte(obj.(Bytes)) },
+		NewJSON:  func() interface{} { return new([]byte) },
+		FromJSON: func(ptr interface{}) Serializer { return Bytes(*ptr.(*[]byte)) },
+	},
+	Int(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return jsonInt64(obj.(Int)) },
+		NewJSON:  func() interface{} { return new(jsonInt64) },
+		FromJSON: func(ptr interface{}) Serializer { return Int(*ptr.(*jsonInt64)) },
+	},
+	IntSlice(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([]jsonInt64, len(obj.(IntSlice)))
+			for i, x := range obj.(IntSlice) {
+				res[i] = jsonInt64(x)
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([]jsonInt64) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(IntSlice, len(*ptr.(*[]jsonInt64)))
+			for i, x := range *ptr.(*[]jsonInt64) {
+				res[i] = int(x)
+			}
+			return res
+		},
+	},
+	Int32(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return int32(obj.(Int32)) },
+		NewJSON:  func() interface{} { return new(int32) },
+		FromJSON: func(ptr interface{}) Serializer { return Int32(*ptr.(*int32)) },
+	},
+	Int32Slice(nil).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return []int32(obj.(Int32Slice)) },
+		NewJSON:  func() interface{} { return new([]int32) },
+		FromJSON: func(ptr interface{}) Serializer { return Int32Slice(*ptr.(*[]int32)) },
+	},
+	Int64(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return jsonInt64(obj.(Int64)) },
+		NewJSON:  func() interface{} { return new(jsonInt64) },
+		FromJSON: func(ptr interface{}) Serializer { return Int64(*ptr.(*jsonInt64)) },
+	},
+	Int64Slice(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([]jsonInt64, len(obj.(Int64Slice)))
+			for i, x := range obj.(Int64Slice) {
+				res[i] = jsonInt64(x)
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([]jsonInt64) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(Int64Slice, len(*ptr.(*[]jsonInt64)))
+			for i, x := range *ptr.(*[]jsonInt64) {
+				res[i] = int64(x)
+			}
+			return res
+		},
+	},
+	Int8(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return int8(obj.(Int8)) },
+		NewJSON:  func() interface{} { return new(int8) },
+		FromJSON: func(ptr interface{}) Serializer { return Int8(*ptr.(*int8)) },
+	},
+	Int8Slice(nil).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return []int8(obj.(Int8Slice)) },
+		NewJSON:  func() interface{} { return new([]int8) },
+		FromJSON: func(ptr interface{}) Serializer { return Int8Slice(*ptr.(*[]int8)) },
+	},
+	Int16(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return int16(obj.(Int16)) },
+		NewJSON:  func() interface{} { return new(int16) },
+		FromJSON: func(ptr interface{}) Serializer { return Int16(*ptr.(*int16)) },
+	},
+	Int16Slice(nil).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return []int16(obj.(Int16Slice)) },
+		NewJSON:  func() interface{} { return new([]int16) },
+		FromJSON: func(ptr interface{}) Serializer { return Int16Slice(*ptr.(*[]int16)) },
+	},
+	Uint(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return jsonUint64(obj.(Uint)) },
+		NewJSON:  func() interface{} { return new(jsonUint64) },
+		FromJSON: func(ptr interface{}) Serializer { return Uint(*ptr.(*jsonUint64)) },
+	},
+	UintSlice(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([]jsonUint64, len(obj.(UintSlice)))
+			for i, x := range obj.(UintSlice) {
+				res[i] = jsonUint64(x)
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([]jsonUint64) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(UintSlice, len(*ptr.(*[]jsonUint64)))
+			for i, x := range *ptr.(*[]jsonUint64) {
+				res[i] = uint(x)
+			}
+			return res
+		},
+	},
+	Uint8(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return uint8(obj.(Uint8)) },
+		NewJSON:  func() interface{} { return new(uint8) },
+		FromJSON: func(ptr interface{}) Serializer { return Uint8(*ptr.(*uint8)) },
+	},
+	Uint16(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return uint16(obj.(Uint16)) },
+		NewJSON:  func() interface{} { return new(uint16) },
+		FromJSON: func(ptr interface{}) Serializer { return Uint16(*ptr.(*uint16)) },
+	},
+	Uint16Slice(nil).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return []uint16(obj.(Uint16Slice)) },
+		NewJSON:  func() interface{} { return new([]uint16) },
+		FromJSON: func(ptr interface{}) Serializer { return Uint16Slice(*ptr.(*[]uint16)) },
+	},
+	Uint32(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return uint32(obj.(Uint32)) },
+		NewJSON:  func() interface{} { return new(uint32) },
+		FromJSON: func(ptr interface{}) Serializer { return Uint32(*ptr.(*uint32)) },
+	},
+	Uint32Slice(nil).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return []uint32(obj.(Uint32Slice)) },
+		NewJSON:  func() interface{} { return new([]uint32) },
+		FromJSON: func(ptr interface{}) Serializer { return Uint32Slice(*ptr.(*[]uint32)) },
+	},
+	Uint64(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return jsonUint64(obj.(Uint64)) },
+		NewJSON:  func() interface{} { return new(jsonUint64) },
+		FromJSON: func(ptr interface{}) Serializer { return Uint64(*ptr.(*jsonUint64)) },
+	},
+	Uint64Slice(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([]jsonUint64, len(obj.(Uint64Slice)))
+			for i, x := range obj.(Uint64Slice) {
+				res[i] = jsonUint64(x)
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([]jsonUint64) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(Uint64Slice, len(*ptr.(*[]jsonUint64)))
+			for i, x := range *ptr.(*[]jsonUint64) {
+				res[i] = uint64(x)
+			}
+			return res
+		},
+	},
+	Duration(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return jsonInt64(obj.(Duration)) },
+		NewJSON:  func() interface{} { return new(jsonInt64) },
+		FromJSON: func(ptr interface{}) Serializer { return Duration(*ptr.(*jsonInt64)) },
+	},
+	Float32(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return jsonFloat32(obj.(Float32)) },
+		NewJSON:  func() interface{} { return new(jsonFloat32) },
+		FromJSON: func(ptr interface{}) Serializer { return Float32(*ptr.(*jsonFloat32)) },
+	},
+	Float32Slice(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([]jsonFloat32, len(obj.(Float32Slice)))
+			for i, x := range obj.(Float32Slice) {
+				res[i] = jsonFloat32(x)
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([]jsonFloat32) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(Float32Slice, len(*ptr.(*[]jsonFloat32)))
+			for i, x := range *ptr.(*[]jsonFloat32) {
+				res[i] = float32(x)
+			}
+			return res
+		},
+	},
+	Float64(0).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return jsonFloat64(obj.(Float64)) },
+		NewJSON:  func() interface{} { return new(jsonFloat64) },
+		FromJSON: func(ptr interface{}) Serializer { return Float64(*ptr.(*jsonFloat64)) },
+	},
+	Float64Slice(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([]jsonFloat64, len(obj.(Float64Slice)))
+			for i, x := range obj.(Float64Slice) {
+				res[i] = jsonFloat64(x)
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([]jsonFloat64) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(Float64Slice, len(*ptr.(*[]jsonFloat64)))
+			for i, x := range *ptr.(*[]jsonFloat64) {
+				res[i] = float64(x)
+			}
+			return res
+		},
+	},
+	StringSlice(nil).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return []string(obj.(StringSlice)) },
+		NewJSON:  func() interface{} { return new([]string) },
+		FromJSON: func(ptr interface{}) Serializer { return StringSlice(*ptr.(*[]string)) },
+	},
+	BoolSlice(nil).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return []bool(obj.(BoolSlice)) },
+		NewJSON:  func() interface{} { return new([]bool) },
+		FromJSON: func(ptr interface{}) Serializer { return BoolSlice(*ptr.(*[]bool)) },
+	},
+	IntSlice2D(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([][]jsonInt64, len(obj.(IntSlice2D)))
+			for i, row := range obj.(IntSlice2D) {
+				res[i] = make([]jsonInt64, len(row))
+				for j, x := range row {
+					res[i][j] = jsonInt64(x)
+				}
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([][]jsonInt64) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(IntSlice2D, len(*ptr.(*[][]jsonInt64)))
+			for i, row := range *ptr.(*[][]jsonInt64) {
+				res[i] = make([]int, len(row))
+				for j, x := range row {
+					res[i][j] = int(x)
+				}
+			}
+			return res
+		},
+	},
+	Float32Slice2D(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([][]jsonFloat32, len(obj.(Float32Slice2D)))
+			for i, row := range obj.(Float32Slice2D) {
+				res[i] = make([]jsonFloat32, len(row))
+				for j, x := range row {
+					res[i][j] = jsonFloat32(x)
+				}
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([][]jsonFloat32) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(Float32Slice2D, len(*ptr.(*[][]jsonFloat32)))
+			for i, row := range *ptr.(*[][]jsonFloat32) {
+				res[i] = make([]float32, len(row))
+				for j, x := range row {
+					res[i][j] = float32(x)
+				}
+			}
+			return res
+		},
+	},
+	Float64Slice2D(nil).SerializerType(): {
+		ToJSON: func(obj Serializer) interface{} {
+			res := make([][]jsonFloat64, len(obj.(Float64Slice2D)))
+			for i, row := range obj.(Float64Slice2D) {
+				res[i] = make([]jsonFloat64, len(row))
+				for j, x := range row {
+					res[i][j] = jsonFloat64(x)
+				}
+			}
+			return res
+		},
+		NewJSON: func() interface{} { return new([][]jsonFloat64) },
+		FromJSON: func(ptr interface{}) Serializer {
+			res := make(Float64Slice2D, len(*ptr.(*[][]jsonFloat64)))
+			for i, row := range *ptr.(*[][]jsonFloat64) {
+				res[i] = make([]float64, len(row))
+				for j, x := range row {
+					res[i][j] = float64(x)
+				}
+			}
+			return res
+		},
+	},
+	Bool(false).SerializerType(): {
+		ToJSON:   func(obj Serializer) interface{} { return bool(obj.(Bool)) },
+		NewJSON:  func() interface{} { return new(bool) },
+		FromJSON: func(ptr interface{}) Serializer { return Bool(*ptr.(*bool)) },
+	},
+}
+
+// ExportJSON converts the output of SerializeAny or
+// SerializeSlice into JSON.
+//
+// The result is an array with one element per object.
+// Each element is a JSON object with a "type" field,
+// holding the type ID, and either a "value" field or a
+// "data" field.
+// The "value" field is used for built-in types (including
+// nested []Serializer and Map values), which are
+// represented structurally.
+// The "data" field is used for all other types, and holds
+// the base64-encoded payload.
+// Floating-point values which JSON cannot represent are
+// stored as the strings "NaN", "+Inf", and "-Inf".
+// 64-bit integers (including Int, Uint, and Duration
+// values) are stored as decimal strings, since JSON
+// readers often parse numbers as float64, which cannot
+// represent integers beyond 2^53 exactly.
+// Complex numbers have no structural form, so they always
+// use the "data" field.
+//
+// The conversion is lossless: ImportJSON reproduces the
+// original data byte for byte.
+func ExportJSON(d []byte) (res []byte, err error) {
+	defer essentials.AddCtxTo("export JSON", &err)
+	nodes, err := exportSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(nodes)
+}
+
+// ExportJSONWithType is like ExportJSON, but it converts
+// the output of SerializeWithType into a single JSON
+// object.
+func ExportJSONWithType(d []byte) (res []byte, err error) {
+	defer essentials.AddCtxTo("export JSON", &err)
+	node, err := exportWithType(d)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(node)
+}
+
+// ImportJSON reverses ExportJSON.
+func ImportJSON(j []byte) (res []byte, err error) {
+	defer essentials.AddCtxTo("import JSON", &err)
+	var nodes []*jsonNode
+	if err := json.Unmarshal(j, &nodes); err != nil {
+		return nil, err
+	}
+	return importSlice(nodes)
+}
+
+// ImportJSONWithType reverses ExportJSONWithType.
+func ImportJSONWithType(j []byte) (res []byte, err error) {
+	defer essentials.AddCtxTo("import JSON", &err)
+	var node jsonNode
+	if err := json.Unmarshal(j, &node); err != nil {
+		return nil, err
+	}
+	return importWithType(&node)
+}
+
+func exportSlice(d []byte) ([]*jsonNode, error) {
+	elems, err := SplitSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*jsonNode, len(elems))
+	for i, elem := range elems {
+		nodes[i], err = exportWithType(elem)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+func exportWithType(d []byte) (*jsonNode, error) {
+	typeID, payload, err := SplitWithType(d)
+	if err != nil {
+		return nil, err
+	}
+	node := &jsonNode{Type: typeID}
+
+	if typeID == slice(nil).SerializerType() {
+		if nodes, err := exportSlice(payload); err == nil {
+			node.Value, err = json.Marshal(nodes)
+			if err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	} else if typeID == Map(nil).SerializerType() {
+		if value, ok := exportMap(payload); ok {
+			node.Value = value
+			return node, nil
+		}
+	} else if value, ok := exportValue(typeID, payload); ok {
+		node.Value = value
+		return node, nil
+	}
+
+	data := base64.StdEncoding.EncodeToString(payload)
+	node.Data = &data
+	return node, nil
+}
+
+// exportMap attempts to produce a JSON object for a Map.
+// It fails if the data is not in the canonical form that
+// importMap produces.
+func exportMap(payload []byte) (json.RawMessage, bool) {
+	elems, err := SplitSlice(payload)
+	if err != nil || len(elems)%2 != 0 {
+		return nil, false
+	}
+	nodes := map[string]*jsonNode{}
+	for i := 0; i < len(elems); i += 2 {
+		typeID, key, err := SplitWithType(elems[i])
+		if err != nil || typeID != String("").SerializerType() {
+			return nil, false
+		}
+		nodes[string(key)], err = exportWithType(elems[i+1])
+		if err != nil {
+			return nil, false
+		}
+	}
+	value, err := json.Marshal(nodes)
+	if err != nil {
+		return nil, false
+	}
+	if reproduced, err := importMap(value); err != nil ||
+		!bytes.Equal(reproduced, payload) {
+		return nil, false
+	}
+	return value, true
+}
+
+// exportValue attempts to produce a structural value for
+// a payload.
+// It fails if the type is unknown or if the value would
+// not reproduce the exact payload.
+func exportValue(typeID string, payload []byte) (json.RawMessage, bool) {
+	codec, ok := jsonCodecs[typeID]
+	if !ok {
+		return nil, false
+	}
+	deserializer := GetDeserializer(typeID)
+	if deserializer == nil {
+		return nil, false
+	}
+	obj, err := deserializer(payload)
+	if err != nil {
+		return nil, false
+	}
+	value, err := json.Marshal(codec.ToJSON(obj))
+	if err != nil {
+		return nil, false
+	}
+	if reproduced, err := importValue(codec, value); err != nil ||
+		!bytes.Equal(reproduced, payload) {
+		return nil, false
+	}
+	return value, true
+}
+
+func importSlice(nodes []*jsonNode) ([]byte, error) {
+	var res []byte
+	for _, node := range nodes {
+		if node == nil {
+			return nil, errors.New("null element")
+		}
+		data, err := importWithType(node)
+		if err != nil {
+			return nil, err
+		}
+		res = helperByteOrder.AppendUint64(res, uint64(len(data)))
+		res = append(res, data...)
+	}
+	return res, nil
+}
+
+func importWithType(node *jsonNode) ([]byte, error) {
+	var payload []byte
+	if node.Value != nil {
+		var err error
+		if node.Type == slice(nil).SerializerType() {
+			var nodes []*jsonNode
+			if err := json.Unmarshal(node.Value, &nodes); err != nil {
+				return nil, err
+			}
+			payload, err = importSlice(nodes)
+		} else if node.Type == Map(nil).SerializerType() {
+			payload, err = importMap(node.Value)
+		} else if codec, ok := jsonCodecs[node.Type]; ok {
+			payload, err = importValue(codec, node.Value)
+		} else {
+			err = errors.New("no structural form for type: " + node.Type)
+		}
+		if err != nil {
+			return nil, essentials.AddCtx(node.Type, err)
+		}
+	} else if node.Data != nil {
+		var err error
+		payload, err = base64.StdEncoding.DecodeString(*node.Data)
+		if err != nil {
+			return nil, essentials.AddCtx(node.Type, err)
+		}
+	}
+
+	res := helperByteOrder.AppendUint32(nil, uint32(len(node.Type)))
+	res = append(res, node.Type...)
+	return append(res, payload...), nil
+}
+
+func importMap(value json.RawMessage) ([]byte, error) {
+	var nodes map[string]*jsonNode
+	if err := json.Unmarshal(value, &nodes); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(nodes))
+	for key := range nodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]*jsonNode, 0, len(nodes)*2)
+	for _, key := range keys {
+		keyValue, _ := json.Marshal(key)
+		pairs = append(pairs, &jsonNode{Type: String("").SerializerType(), Value: keyValue},
+			nodes[key])
+	}
+	return importSlice(pairs)
+}
+
+func importValue(codec jsonCodec, value json.RawMessage) ([]byte, error) {
+	ptr := codec.NewJSON()
+	if err := json.Unmarshal(value, ptr); err != nil {
+		return nil, err
+	}
+	return codec.FromJSON(ptr).Serialize()
+}
+
+// jsonFloat64 is a float64 which can represent NaN and
+// infinities in JSON.
+type jsonFloat64 float64
+
+func (j jsonFloat64) MarshalJSON() ([]byte, error) {
+	return marshalJSONFloat(float64(j), 64)
+}
+
+func (j *jsonFloat64) UnmarshalJSON(d []byte) error {
+	x, err := unmarshalJSONFloat(d, 64)
+	*j = jsonFloat64(x)
+	return err
+}
+
+// jsonFloat32 is like jsonFloat64 for float32.
+type jsonFloat32 float32
+
+func (j jsonFloat32) MarshalJSON() ([]byte, error) {
+	return marshalJSONFloat(float64(j), 32)
+}
+
+func (j *jsonFloat32) UnmarshalJSON(d []byte) error {
+	x, err := unmarshalJSONFloat(d, 32)
+	*j = jsonFloat32(x)
+	return err
+}
+
+// jsonInt64 is an int64 which is encoded in JSON as a
+// string to preserve its precision.
+// Numbers are also accepted when decoding.
+type jsonInt64 int64
+
+func (j jsonInt64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(j), 10))
+}
+
+func (j *jsonInt64) UnmarshalJSON(d []byte) error {
+	x, err := strconv.ParseInt(unquoteJSONNumber(d), 10, 64)
+	*j = jsonInt64(x)
+	return err
+}
+
+// jsonUint64 is like jsonInt64 for uint64.
+type jsonUint64 uint64
+
+func (j jsonUint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(j), 10))
+}
+
+func (j *jsonUint64) UnmarshalJSON(d []byte) error {
+	x, err := strconv.ParseUint(unquoteJSONNumber(d), 10, 64)
+	*j = jsonUint64(x)
+	return err
+}
+
+func unquoteJSONNumber(d []byte) string {
+	var str string
+	if json.Unmarshal(d, &str) == nil {
+		return str
+	}
+	return string(d)
+}
+
+func marshalJSONFloat(x float64, bits int) ([]byte, error) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return json.Marshal(strconv.FormatFloat(x, 'g', -1, bits))
+	}
+	return []byte(strconv.FormatFloat(x, 'g', -1, bits)), nil
+}
+
+func unmarshalJSONFloat(d []byte, bits int) (float64, error) {
+	return strconv.ParseFloat(unquoteJSONNumber(d), bits)
+}
@@ -0,0 +1,143 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	data, err := SerializeAny(
+		"hello",
+		string([]byte{0xff, 0xfe}),
+		[]byte{1, 2, 3},
+		-15,
+		[]int{1, -2},
+		int32(-7),
+		[]int32{},
+		int64(1<<62+1),
+		[]int64{1 << 60},
+		float32(3.25),
+		[]float32{float32(math.Inf(1)), -0.5},
+		math.Copysign(0, -1),
+		[]float64{math.NaN(), math.Inf(-1), 1e300},
+		true,
+		[]Serializer{Int(1), slice{}, &demoType1{X: 3}},
+		&rawTypedData{typeID: "int", data: []byte("007")},
+		[]string{"a", "b"},
+		[]bool{true, false, true},
+		[][]float32{{1, float32(math.NaN())}, {}},
+		[][]float64{{-0.5}},
+		[][]int{{1, 2}, {3}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ExportJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ImportJSON(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(imported, data) {
+		t.Errorf("round trip mismatch for JSON: %s", exported)
+	}
+
+	var nodes []map[string]interface{}
+	if err := json.Unmarshal(exported, &nodes); err != nil {
+		t.Fatal(err)
+	}
+	if nodes[0]["value"] != "hello" {
+		t.Errorf("unexpected node: %v", nodes[0])
+	}
+	if _, ok := nodes[1]["data"]; !ok {
+		t.Errorf("invalid UTF-8 should be opaque: %v", nodes[1])
+	}
+	if nodes[3]["value"] != "-15" {
+		t.Errorf("unexpected node: %v", nodes[3])
+	}
+	if nodes[7]["value"] != "4611686018427387905" {
+		t.Errorf("unexpected node: %v", nodes[7])
+	}
+	if _, ok := nodes[15]["data"]; !ok {
+		t.Errorf("non-canonical int should be opaque: %v", nodes[15])
+	}
+
+	single, err := SerializeWithType(Float64Slice{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err = ExportJSONWithType(single)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(exported) != `{"type":"[]float64","value":[1,2]}` {
+		t.Errorf("unexpected JSON: %s", exported)
+	}
+	imported, err = ImportJSONWithType(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(imported, single) {
+		t.Error("round trip mismatch for single object")
+	}
+}
+
+func TestJSONMap(t *testing.T) {
+	data, err := SerializeAny(Map{"b": Int(2), "a": Map{"c": Float64(1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ExportJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"type":"map[string]Serializer","value":{"a":{"type":"map[string]Serializer",` +
+		`"value":{"c":{"type":"float64","value":1}}},"b":{"type":"int","value":"2"}}}]`
+	if string(exported) != expected {
+		t.Errorf("unexpected JSON: %s", exported)
+	}
+	imported, err := ImportJSON(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(imported, data) {
+		t.Error("round trip mismatch")
+	}
+}
+
+func TestJSONSizedPrimitives(t *testing.T) {
+	data, err := SerializeAny(uint64(1<<64-1), []int8{-128, 127}, uint8(200),
+		complex128(1+2i))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ExportJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"type":"uint64","value":"18446744073709551615"},` +
+		`{"type":"[]int8","value":[-128,127]},{"type":"uint8","value":200},` +
+		`{"type":"complex128","data":"AAAAAAAA8D8AAAAAAAAAQA=="}]`
+	if string(exported) != expected {
+		t.Errorf("unexpected JSON: %s", exported)
+	}
+	imported, err := ImportJSON(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(imported, data) {
+		t.Error("round trip mismatch")
+	}
+
+	imported, err = ImportJSON([]byte(`[{"type":"uint64","value":18446744073709551615}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(imported, data[:len(imported)]) {
+		t.Error("numeric JSON value was not accepted")
+	}
+}
@@ -0,0 +1,169 @@
+package serializer
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecodeLimits restricts the resources used while
+// decoding, protecting against hostile or corrupt input.
+//
+// Each Registry has its own limits, which are enforced
+// by its decoding routines (such as DeserializeWithType)
+// and passed on to nested objects through the
+// DecodeContext.
+// A Deserializer which decodes nested objects with the
+// global functions escapes the limits of the Registry
+// that called it, including the nesting depth.
+//
+// A zero field means that the corresponding quantity is
+// unlimited.
+// Even without limits, the built-in deserializers never
+// allocate more elements than the input could possibly
+// contain.
+type DecodeLimits struct {
+	// MaxElements is the maximum number of elements in a
+	// decoded slice, map, or tensor, including []Serializer
+	// values and the built-in slice types such as
+	// Float64Slice.
+	// Byte slices are limited by MaxBytes instead.
+	MaxElements int
+
+	// MaxBytes is the maximum size of the data for a single
+	// object, including objects read by a Decoder and the
+	// output of decompression.
+	MaxBytes int
+
+	// MaxDepth is the maximum nesting depth of objects
+	// decoded through a DecodeContext, such as the elements
+	// of []Serializer values and the objects inside
+	// wrappers like Compressed.
+	MaxDepth int
+
+	// MaxTypeIDLength is the maximum length of a type ID.
+	MaxTypeIDLength int
+}
+
+// SetDecodeLimits sets the limits used when decoding
+// with r.
+//
+// It is safe to call concurrently with decoding routines.
+func (r *Registry) SetDecodeLimits(l DecodeLimits) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.limits = l
+}
+
+// GetDecodeLimits returns the limits set by
+// SetDecodeLimits.
+func (r *Registry) GetDecodeLimits() DecodeLimits {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.limits
+}
+
+// Limits returns the limits of the Registry in use.
+func (c *DecodeContext) Limits() DecodeLimits {
+	return c.registry.GetDecodeLimits()
+}
+
+// SetDecodeLimits sets the limits of the DefaultRegistry.
+// See Registry.SetDecodeLimits for details.
+func SetDecodeLimits(l DecodeLimits) {
+	DefaultRegistry.SetDecodeLimits(l)
+}
+
+// GetDecodeLimits returns the limits of the
+// DefaultRegistry.
+func GetDecodeLimits() DecodeLimits {
+	return DefaultRegistry.GetDecodeLimits()
+}
+
+// A LimitError is returned when decoding exceeds one of
+// the DecodeLimits.
+type LimitError struct {
+	// Limit is the name of the DecodeLimits field.
+	Limit string
+
+	// Value is the offending quantity.
+	Value uint64
+
+	// Max is the value of the limit.
+	Max int
+}
+
+// Error returns the error message.
+func (l *LimitError) Error() string {
+	return fmt.Sprintf("decode limit exceeded: %s is %d (max %d)", l.Limit,
+		l.Value, l.Max)
+}
+
+func (d DecodeLimits) checkBytes(n uint64) error {
+	return checkLimit("MaxBytes", n, d.MaxBytes)
+}
+
+func (d DecodeLimits) checkDepth(depth int) error {
+	return checkLimit("MaxDepth", uint64(depth), d.MaxDepth)
+}
+
+func (d DecodeLimits) checkTypeIDLength(n int) error {
+	return checkLimit("MaxTypeIDLength", uint64(n), d.MaxTypeIDLength)
+}
+
+func (d DecodeLimits) checkElements(n uint64) error {
+	return checkLimit("MaxElements", n, d.MaxElements)
+}
+
+// checkObject checks the number of elements in a decoded
+// object, including the rows of a 2D slice.
+func (d DecodeLimits) checkObject(obj Serializer) error {
+	if d.MaxElements <= 0 {
+		return nil
+	}
+	if t, ok := obj.(*Tensor); ok && t != nil {
+		return d.checkElements(uint64(t.NumElements()))
+	}
+	val := reflect.ValueOf(obj)
+	switch val.Kind() {
+	case reflect.Map:
+		return d.checkElements(uint64(val.Len()))
+	case reflect.Slice:
+		elemKind := val.Type().Elem().Kind()
+		if elemKind == reflect.Uint8 {
+			return nil
+		}
+		if err := d.checkElements(uint64(val.Len())); err != nil {
+			return err
+		}
+		if elemKind == reflect.Slice {
+			for i := 0; i < val.Len(); i++ {
+				if err := d.checkElements(uint64(val.Index(i).Len())); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func checkLimit(name string, value uint64, max int) error {
+	if max > 0 && value > uint64(max) {
+		return &LimitError{Limit: name, Value: value, Max: max}
+	}
+	return nil
+}
+
+// checkElementCount validates the decoded size of a slice
+// before it is allocated.
+// It ensures that the remaining data can hold that many
+// elements of the given size.
+//
+// The MaxElements limit is checked by the Registry once
+// the object has been decoded, which is safe since the
+// allocation is bounded by the size of the data.
+func checkElementCount(size uint64, elemSize, remaining int) (int, error) {
+	if size > uint64(remaining)/uint64(elemSize) {
+		return 0, ErrBufferUnderflow
+	}
+	return int(size), nil
+}
@@ -0,0 +1,92 @@
+package serializer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCorruptSliceSizes(t *testing.T) {
+	data := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 1, 2, 3, 4, 5, 6, 7, 8}
+	if _, err := DeserializeIntSlice(data); err == nil {
+		t.Error("expected error for IntSlice")
+	}
+	if _, err := DeserializeInt32Slice(data); err == nil {
+		t.Error("expected error for Int32Slice")
+	}
+	if _, err := DeserializeInt64Slice(data); err == nil {
+		t.Error("expected error for Int64Slice")
+	}
+	if _, err := DeserializeFloat32Slice(data); err == nil {
+		t.Error("expected error for Float32Slice")
+	}
+	if _, err := DeserializeFloat64Slice(data); err == nil {
+		t.Error("expected error for Float64Slice")
+	}
+
+	data = []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1, 2, 3, 4, 5, 6, 7, 8}
+	if _, err := DeserializeSlice(data); err == nil {
+		t.Error("expected error for DeserializeSlice")
+	}
+	if _, err := NewDecoder(bytes.NewReader(data)).Decode(); err == nil {
+		t.Error("expected error for Decoder")
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	nested := []Serializer{Int(1), slice{Int(2), slice{Int(3)}}}
+	data, err := SerializeAny(nested, Float64Slice{1, 2, 3}, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	floats, err := SerializeAny(Float64Slice{1, 2, 3}, Float64Slice2D{{1}, {2, 3, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := SerializeAny(&Compressed{Codec: CodecGzip, Value: make(Bytes, 1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	structs, err := SerializeAny(&structTestType{
+		Slice: []Serializer{&structTestType{Slice: []Serializer{Int(3)}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		limits DecodeLimits
+		data   []byte
+		limit  string
+	}{
+		{DecodeLimits{MaxDepth: 2}, data, "MaxDepth"},
+		{DecodeLimits{MaxDepth: 3}, structs, "MaxDepth"},
+		{DecodeLimits{MaxElements: 2}, data, "MaxElements"},
+		{DecodeLimits{MaxElements: 2}, floats, "MaxElements"},
+		{DecodeLimits{MaxBytes: 50}, data, "MaxBytes"},
+		{DecodeLimits{MaxBytes: 500}, compressed, "MaxBytes"},
+		{DecodeLimits{MaxTypeIDLength: 8}, data, "MaxTypeIDLength"},
+	}
+	for _, test := range tests {
+		r := DefaultRegistry.Clone()
+		r.SetDecodeLimits(test.limits)
+		_, err := r.DeserializeSlice(test.data)
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != test.limit {
+			t.Errorf("limit %s: unexpected error: %v", test.limit, err)
+		}
+		if _, err := DeserializeSlice(test.data); err != nil {
+			t.Errorf("limit %s: DefaultRegistry was affected: %v", test.limit, err)
+		}
+	}
+
+	r := DefaultRegistry.Clone()
+	r.SetDecodeLimits(DecodeLimits{MaxDepth: 3, MaxElements: 3, MaxBytes: 1000,
+		MaxTypeIDLength: 12})
+	if _, err := r.DeserializeSlice(data); err != nil {
+		t.Error(err)
+	}
+	if r.Clone().GetDecodeLimits() != r.GetDecodeLimits() {
+		t.Error("limits were not cloned")
+	}
+}
@@ -0,0 +1,128 @@
+package serializer
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	RegisterContextDeserializer(Map(nil).SerializerType(), func(d []byte,
+		ctx *DecodeContext) (Serializer, error) {
+		res, err := deserializeMap(d, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	})
+}
+
+// Map is a Serializer for a map[string]Serializer.
+//
+// Keys are stored in sorted order, so equal maps always
+// produce identical data.
+type Map map[string]Serializer
+
+// DeserializeMap deserializes a Map.
+func DeserializeMap(d []byte) (Map, error) {
+	return deserializeMap(d, &DecodeContext{registry: DefaultRegistry})
+}
+
+// Serialize serializes the object.
+func (m Map) Serialize() ([]byte, error) {
+	return m.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (m Map) AppendSerialize(dst []byte) ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]Serializer, 0, len(m)*2)
+	for _, key := range keys {
+		pairs = append(pairs, String(key), m[key])
+	}
+	return AppendSlice(dst, pairs)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Map.
+func (m Map) SerializerType() string {
+	return "map[string]Serializer"
+}
+
+// Copy creates a deep copy of the map, copying the values
+// with Copy.
+func (m Map) Copy() (interface{}, error) {
+	if m == nil {
+		return m, nil
+	}
+	res := make(Map, len(m))
+	for key, value := range m {
+		copied, err := copySerializer(value)
+		if err != nil {
+			return nil, essentials.AddCtx("map key "+key, err)
+		}
+		res[key] = copied
+	}
+	return res, nil
+}
+
+func deserializeMap(d []byte, ctx *DecodeContext) (res Map, err error) {
+	defer essentials.AddCtxTo("deserialize map", &err)
+	pairs, err := ctx.DeserializeSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("odd number of objects")
+	}
+	res = Map{}
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(String)
+		if !ok {
+			return nil, errors.New("key is not a string")
+		}
+		if _, ok := res[string(key)]; ok {
+			return nil, errors.New("duplicate key: " + string(key))
+		}
+		res[string(key)] = pairs[i+1]
+	}
+	return res, nil
+}
+
+// mapToSerializer converts a map with string keys into a
+// Map, converting the values with toSerializer.
+func mapToSerializer(val reflect.Value) (Serializer, error) {
+	res := make(Map, val.Len())
+	iter := val.MapRange()
+	for iter.Next() {
+		value, err := toSerializer(iter.Value().Interface())
+		if err != nil {
+			return nil, essentials.AddCtx("map value", err)
+		}
+		res[iter.Key().String()] = value
+	}
+	return res, nil
+}
+
+// assignMap stores a decoded Map in a map with string
+// keys, converting the values with assignDecoded.
+func assignMap(m Map, dest reflect.Value) error {
+	res := reflect.MakeMapWithSize(dest.Type(), len(m))
+	keyType := dest.Type().Key()
+	valueType := dest.Type().Elem()
+	for key, value := range m {
+		elem := reflect.New(valueType)
+		if err := assignDecoded(value, elem.Interface()); err != nil {
+			return essentials.AddCtx("map key "+key, err)
+		}
+		res.SetMapIndex(reflect.ValueOf(key).Convert(keyType), elem.Elem())
+	}
+	dest.Set(res)
+	return nil
+}
@@ -0,0 +1,79 @@
+package serializer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	m := Map{"b": Int(2), "a": String("x"), "c": slice{Bool(true)}}
+	data1, err := m.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		data2, _ := Map{"c": slice{Bool(true)}, "a": String("x"), "b": Int(2)}.Serialize()
+		if !bytes.Equal(data1, data2) {
+			t.Fatal("serialization is not deterministic")
+		}
+	}
+	decoded, err := DeserializeMap(data1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, m) {
+		t.Errorf("expected %v but got %v", m, decoded)
+	}
+
+	floats := Map{"f": Float64Slice{1, 2}, "g": Float32Slice{3}}
+	standalone, err := floats.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []interface{}{"x", "xy", Float32Slice{1}} {
+		embedded, err := SerializeAny(prefix, floats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasSuffix(embedded, standalone) {
+			t.Errorf("prefix %v: embedded data differs from standalone data", prefix)
+		}
+	}
+
+	bad, _ := SerializeAny("a", 1, "a", 2)
+	if _, err := DeserializeMap(bad); err == nil {
+		t.Error("expected error for duplicate key")
+	}
+}
+
+func TestMapSerializeAny(t *testing.T) {
+	ints := map[string]int{"x": 1, "y": -2}
+	floats := map[string][]float64{"w": {1, 2}}
+	data, err := SerializeAny(ints, floats, map[string]Serializer{"s": String("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outInts map[string]int
+	var outFloats map[string]Float64Slice
+	var outMap Map
+	if err := DeserializeAny(data, &outInts, &outFloats, &outMap); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(outInts, ints) {
+		t.Errorf("expected %v but got %v", ints, outInts)
+	}
+	if !reflect.DeepEqual(outFloats, map[string]Float64Slice{"w": {1, 2}}) {
+		t.Errorf("unexpected floats: %v", outFloats)
+	}
+	if !reflect.DeepEqual(outMap, Map{"s": String("hi")}) {
+		t.Errorf("unexpected map: %v", outMap)
+	}
+
+	var wrongType map[string]bool
+	err = DeserializeAny(data, &wrongType, &outFloats, &outMap)
+	if err == nil || !strings.Contains(err.Error(), "element 0") {
+		t.Errorf("expected type error but got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package serializer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/unixpickle/essentials"
+)
+
+// A MappedFile is a file which has been mapped into
+// memory by LoadAnyMapped.
+type MappedFile struct {
+	data []byte
+}
+
+// Bytes returns the contents of the file.
+func (m *MappedFile) Bytes() []byte {
+	return m.data
+}
+
+// Close unmaps the file.
+//
+// Objects loaded from the file may share memory with it,
+// so they must not be used after Close is called.
+func (m *MappedFile) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	data := m.data
+	m.data = nil
+	return unmapFile(data)
+}
+
+// LoadAnyMapped is like LoadAny, but it maps the file into
+// memory rather than reading it onto the heap, and it
+// decodes the objects with zero-copy decoding enabled (see
+// Registry.SetZeroCopy).
+//
+// This makes it possible to load very large files without
+// copying their contents.
+// On platforms without mmap support, the file is read
+// into memory instead.
+//
+// The mapping is private, so modifying the loaded objects
+// does not modify the file.
+//
+// Loaded objects may point into the mapping, including
+// Bytes values, the Data of Tensors, and numeric slices
+// (such as []float32) whose elements happen to be aligned
+// in the file.
+// Accessing such objects after the returned MappedFile is
+// closed may crash the program, so the caller should only
+// Close it once the loaded objects are no longer used, or
+// after copying them (see Copy).
+func LoadAnyMapped(path string, objOut ...interface{}) (mapped *MappedFile, err error) {
+	defer essentials.AddCtxTo("LoadAnyMapped", &err)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(info.Size())
+	if int64(size) != info.Size() {
+		return nil, fmt.Errorf("file too large to map: %d bytes", info.Size())
+	}
+
+	mapped = &MappedFile{data: []byte{}}
+	if size > 0 {
+		mapped.data, err = mapFile(f, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		if err != nil {
+			mapped.Close()
+		}
+	}()
+
+	contents := mapped.data
+	if IsContainer(contents) {
+		_, contents, err = ReadContainer(contents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	registry := DefaultRegistry.Clone()
+	registry.SetZeroCopy(true)
+	if err := registry.DeserializeAny(contents, objOut...); err != nil {
+		return nil, err
+	}
+	return mapped, nil
+}
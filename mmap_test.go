@@ -0,0 +1,71 @@
+package serializer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestLoadAnyMapped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serializer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	weights := []float32{1, 2, 3, 4}
+	for i, opts := range []*SaveOptions{
+		nil,
+		{Container: &ContainerInfo{}},
+		{Container: &ContainerInfo{Checksum: ChecksumSHA256,
+			Metadata: map[string]string{"a": "bc"}}},
+		{Container: &ContainerInfo{Compression: CodecGzip}},
+	} {
+		path := filepath.Join(dir, "file")
+		if err := SaveAnyWithOptions(path, opts, "x", weights, "name"); err != nil {
+			t.Fatal(err)
+		}
+		var outPrefix string
+		var outWeights []float32
+		var outName string
+		mapped, err := LoadAnyMapped(path, &outPrefix, &outWeights, &outName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(outWeights, weights) || outName != "name" {
+			t.Errorf("case %d: unexpected result: %v %v", i, outWeights, outName)
+		}
+
+		data := mapped.Bytes()
+		start := uintptr(unsafe.Pointer(&data[0]))
+		ptr := uintptr(unsafe.Pointer(&outWeights[0]))
+		shared := ptr >= start && ptr < start+uintptr(len(data))
+		compressed := opts != nil && opts.Container.Compression != CodecNone
+		elems, _ := Float32Slice(weights).Serialize()
+		aligned := false
+		if idx := bytes.Index(data, elems[8:]); idx >= 0 {
+			aligned = uintptr(unsafe.Pointer(&data[idx]))%4 == 0
+		}
+		if shared != (littleEndianHost && !compressed && aligned) {
+			t.Errorf("case %d: unexpected sharing: %v", i, shared)
+		}
+
+		if err := mapped.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "empty")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := LoadAnyMapped(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped.Close()
+}
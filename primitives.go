@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"math"
 	"strconv"
 
 	"github.com/unixpickle/essentials"
@@ -16,12 +17,8 @@ func init() {
 	RegisterTypedDeserializer(IntSlice(nil).SerializerType(), DeserializeIntSlice)
 	RegisterTypedDeserializer(Int64(0).SerializerType(), DeserializeInt64)
 	RegisterTypedDeserializer(Int32(0).SerializerType(), DeserializeInt32)
-	RegisterTypedDeserializer(Int64Slice(nil).SerializerType(), DeserializeInt64Slice)
-	RegisterTypedDeserializer(Int32Slice(nil).SerializerType(), DeserializeInt32Slice)
 	RegisterTypedDeserializer(Float64(0).SerializerType(), DeserializeFloat64)
 	RegisterTypedDeserializer(Float32(0).SerializerType(), DeserializeFloat32)
-	RegisterTypedDeserializer(Float64Slice(nil).SerializerType(), DeserializeFloat64Slice)
-	RegisterTypedDeserializer(Float32Slice(nil).SerializerType(), DeserializeFloat32Slice)
 	RegisterTypedDeserializer(Bool(false).SerializerType(), DeserializeBool)
 }
 
@@ -38,12 +35,22 @@ func (b Bytes) Serialize() ([]byte, error) {
 	return b, nil
 }
 
+// AppendSerialize appends the serialized object to dst.
+func (b Bytes) AppendSerialize(dst []byte) ([]byte, error) {
+	return append(dst, b...), nil
+}
+
 // SerializerType returns the unique ID used to serialize
 // Bytes.
 func (b Bytes) SerializerType() string {
 	return "[]byte"
 }
 
+// Copy creates a copy of the slice.
+func (b Bytes) Copy() (interface{}, error) {
+	return append(b[:0:0], b...), nil
+}
+
 // String is a Serializer wrapper for string.
 type String string
 
@@ -57,12 +64,22 @@ func (s String) Serialize() ([]byte, error) {
 	return []byte(s), nil
 }
 
+// AppendSerialize appends the serialized object to dst.
+func (s String) AppendSerialize(dst []byte) ([]byte, error) {
+	return append(dst, s...), nil
+}
+
 // SerializerType returns the unique ID used to serialize
 // a String.
 func (s String) SerializerType() string {
 	return "string"
 }
 
+// Copy returns the object itself.
+func (s String) Copy() (interface{}, error) {
+	return s, nil
+}
+
 // Int is a Serializer wrapper for an int.
 type Int int
 
@@ -77,7 +94,12 @@ func DeserializeInt(d []byte) (Int, error) {
 
 // Serialize serializes the object.
 func (i Int) Serialize() ([]byte, error) {
-	return []byte(strconv.Itoa(int(i))), nil
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int) AppendSerialize(dst []byte) ([]byte, error) {
+	return strconv.AppendInt(dst, int64(i), 10), nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -86,6 +108,11 @@ func (i Int) SerializerType() string {
 	return "int"
 }
 
+// Copy returns the object itself.
+func (i Int) Copy() (interface{}, error) {
+	return i, nil
+}
+
 // IntSlice is a Serializer wrapper for an []int.
 type IntSlice []int
 
@@ -99,7 +126,11 @@ func DeserializeIntSlice(d []byte) (slice IntSlice, err error) {
 	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
 		return nil, err
 	}
-	vec := make([]int64, int(size))
+	n, err := checkElementCount(size, 8, reader.Len())
+	if err != nil {
+		return nil, err
+	}
+	vec := make([]int64, n)
 	if err := binary.Read(reader, binary.LittleEndian, vec); err != nil {
 		return nil, err
 	}
@@ -114,14 +145,17 @@ func DeserializeIntSlice(d []byte) (slice IntSlice, err error) {
 
 // Serialize serializes the object.
 func (i IntSlice) Serialize() ([]byte, error) {
-	ints64 := make([]int64, len(i))
-	for j, x := range i {
-		ints64[j] = int64(x)
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i IntSlice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+8*len(i))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(i)))
+	for _, x := range i {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(x))
 	}
-	var w bytes.Buffer
-	binary.Write(&w, binary.LittleEndian, uint64(len(i)))
-	binary.Write(&w, binary.LittleEndian, ints64)
-	return w.Bytes(), nil
+	return dst, nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -130,6 +164,11 @@ func (i IntSlice) SerializerType() string {
 	return "[]int"
 }
 
+// Copy creates a copy of the slice.
+func (i IntSlice) Copy() (interface{}, error) {
+	return append(i[:0:0], i...), nil
+}
+
 // Int64 is a Serializer for a int64.
 type Int64 int64
 
@@ -145,9 +184,12 @@ func DeserializeInt64(d []byte) (Int64, error) {
 
 // Serialize serializes the object.
 func (i Int64) Serialize() ([]byte, error) {
-	var buf bytes.Buffer
-	binary.Write(&buf, binary.LittleEndian, int64(i))
-	return buf.Bytes(), nil
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int64) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint64(dst, uint64(i)), nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -156,6 +198,11 @@ func (i Int64) SerializerType() string {
 	return "int64"
 }
 
+// Copy returns the object itself.
+func (i Int64) Copy() (interface{}, error) {
+	return i, nil
+}
+
 // Int32 is a Serializer for a int32.
 type Int32 int32
 
@@ -171,9 +218,12 @@ func DeserializeInt32(d []byte) (Int32, error) {
 
 // Serialize serializes the object.
 func (i Int32) Serialize() ([]byte, error) {
-	var buf bytes.Buffer
-	binary.Write(&buf, binary.LittleEndian, int32(i))
-	return buf.Bytes(), nil
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int32) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint32(dst, uint32(i)), nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -182,29 +232,40 @@ func (i Int32) SerializerType() string {
 	return "int32"
 }
 
+// Copy returns the object itself.
+func (i Int32) Copy() (interface{}, error) {
+	return i, nil
+}
+
 // A Int64Slice is a Serializer for a []int64.
 type Int64Slice []int64
 
 // DeserializeInt64Slice deserializes a Int64Slice.
 func DeserializeInt64Slice(d []byte) (Int64Slice, error) {
-	reader := bytes.NewBuffer(d)
-	var size uint64
-	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+	body, n, err := viewData(d, 8)
+	if err != nil {
 		return nil, essentials.AddCtx("deserialize []int64", err)
 	}
-	vec := make([]int64, int(size))
-	if err := binary.Read(reader, binary.LittleEndian, vec); err != nil {
-		return nil, essentials.AddCtx("deserialize []int64", err)
+	vec := make([]int64, n)
+	for j := range vec {
+		vec[j] = int64(binary.LittleEndian.Uint64(body[j*8:]))
 	}
 	return vec, nil
 }
 
 // Serialize serializes the object.
 func (i Int64Slice) Serialize() ([]byte, error) {
-	var w bytes.Buffer
-	binary.Write(&w, binary.LittleEndian, uint64(len(i)))
-	binary.Write(&w, binary.LittleEndian, []int64(i))
-	return w.Bytes(), nil
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int64Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+8*len(i))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(i)))
+	for _, x := range i {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(x))
+	}
+	return dst, nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -213,29 +274,40 @@ func (i Int64Slice) SerializerType() string {
 	return "[]int64"
 }
 
+// Copy creates a copy of the slice.
+func (i Int64Slice) Copy() (interface{}, error) {
+	return append(i[:0:0], i...), nil
+}
+
 // A Int32Slice is a Serializer for a []int32.
 type Int32Slice []int32
 
 // DeserializeInt32Slice deserializes a Int32Slice.
 func DeserializeInt32Slice(d []byte) (Int32Slice, error) {
-	reader := bytes.NewBuffer(d)
-	var size uint64
-	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+	body, n, err := viewData(d, 4)
+	if err != nil {
 		return nil, essentials.AddCtx("deserialize []int32", err)
 	}
-	vec := make([]int32, int(size))
-	if err := binary.Read(reader, binary.LittleEndian, vec); err != nil {
-		return nil, essentials.AddCtx("deserialize []int32", err)
+	vec := make([]int32, n)
+	for j := range vec {
+		vec[j] = int32(binary.LittleEndian.Uint32(body[j*4:]))
 	}
 	return vec, nil
 }
 
 // Serialize serializes the object.
 func (i Int32Slice) Serialize() ([]byte, error) {
-	var w bytes.Buffer
-	binary.Write(&w, binary.LittleEndian, uint64(len(i)))
-	binary.Write(&w, binary.LittleEndian, []int32(i))
-	return w.Bytes(), nil
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int32Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+4*len(i))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(i)))
+	for _, x := range i {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(x))
+	}
+	return dst, nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -244,6 +316,11 @@ func (i Int32Slice) SerializerType() string {
 	return "[]int32"
 }
 
+// Copy creates a copy of the slice.
+func (i Int32Slice) Copy() (interface{}, error) {
+	return append(i[:0:0], i...), nil
+}
+
 // Float64 is a Serializer for a float64.
 type Float64 float64
 
@@ -259,9 +336,12 @@ func DeserializeFloat64(d []byte) (Float64, error) {
 
 // Serialize serializes the object.
 func (f Float64) Serialize() ([]byte, error) {
-	var buf bytes.Buffer
-	binary.Write(&buf, binary.LittleEndian, float64(f))
-	return buf.Bytes(), nil
+	return f.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (f Float64) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint64(dst, math.Float64bits(float64(f))), nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -270,6 +350,11 @@ func (f Float64) SerializerType() string {
 	return "float64"
 }
 
+// Copy returns the object itself.
+func (f Float64) Copy() (interface{}, error) {
+	return f, nil
+}
+
 // Float32 is a Serializer for a float32.
 type Float32 float32
 
@@ -285,9 +370,12 @@ func DeserializeFloat32(d []byte) (Float32, error) {
 
 // Serialize serializes the object.
 func (f Float32) Serialize() ([]byte, error) {
-	var buf bytes.Buffer
-	binary.Write(&buf, binary.LittleEndian, float32(f))
-	return buf.Bytes(), nil
+	return f.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (f Float32) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(f))), nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -296,29 +384,40 @@ func (f Float32) SerializerType() string {
 	return "float32"
 }
 
+// Copy returns the object itself.
+func (f Float32) Copy() (interface{}, error) {
+	return f, nil
+}
+
 // A Float64Slice is a Serializer for a []float64.
 type Float64Slice []float64
 
 // DeserializeFloat64Slice deserializes a Float64Slice.
 func DeserializeFloat64Slice(d []byte) (Float64Slice, error) {
-	reader := bytes.NewBuffer(d)
-	var size uint64
-	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+	body, n, err := viewData(d, 8)
+	if err != nil {
 		return nil, essentials.AddCtx("deserialize []float64", err)
 	}
-	vec := make([]float64, int(size))
-	if err := binary.Read(reader, binary.LittleEndian, vec); err != nil {
-		return nil, essentials.AddCtx("deserialize []float64", err)
+	vec := make([]float64, n)
+	for j := range vec {
+		vec[j] = math.Float64frombits(binary.LittleEndian.Uint64(body[j*8:]))
 	}
 	return vec, nil
 }
 
 // Serialize serializes the object.
 func (f Float64Slice) Serialize() ([]byte, error) {
-	var w bytes.Buffer
-	binary.Write(&w, binary.LittleEndian, uint64(len(f)))
-	binary.Write(&w, binary.LittleEndian, []float64(f))
-	return w.Bytes(), nil
+	return f.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (f Float64Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+8*len(f))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(f)))
+	for _, x := range f {
+		dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(x))
+	}
+	return dst, nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -327,29 +426,40 @@ func (f Float64Slice) SerializerType() string {
 	return "[]float64"
 }
 
+// Copy creates a copy of the slice.
+func (f Float64Slice) Copy() (interface{}, error) {
+	return append(f[:0:0], f...), nil
+}
+
 // A Float32Slice is a Serializer for a []float32.
 type Float32Slice []float32
 
 // DeserializeFloat32Slice deserializes a Float32Slice.
 func DeserializeFloat32Slice(d []byte) (Float32Slice, error) {
-	reader := bytes.NewBuffer(d)
-	var size uint64
-	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+	body, n, err := viewData(d, 4)
+	if err != nil {
 		return nil, essentials.AddCtx("deserialize []float32", err)
 	}
-	vec := make([]float32, int(size))
-	if err := binary.Read(reader, binary.LittleEndian, vec); err != nil {
-		return nil, essentials.AddCtx("deserialize []float32", err)
+	vec := make([]float32, n)
+	for j := range vec {
+		vec[j] = math.Float32frombits(binary.LittleEndian.Uint32(body[j*4:]))
 	}
 	return vec, nil
 }
 
 // Serialize serializes the object.
 func (f Float32Slice) Serialize() ([]byte, error) {
-	var w bytes.Buffer
-	binary.Write(&w, binary.LittleEndian, uint64(len(f)))
-	binary.Write(&w, binary.LittleEndian, []float32(f))
-	return w.Bytes(), nil
+	return f.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (f Float32Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+4*len(f))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(f)))
+	for _, x := range f {
+		dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(x))
+	}
+	return dst, nil
 }
 
 // SerializerType returns the unique ID used to serialize
@@ -358,6 +468,11 @@ func (f Float32Slice) SerializerType() string {
 	return "[]float32"
 }
 
+// Copy creates a copy of the slice.
+func (f Float32Slice) Copy() (interface{}, error) {
+	return append(f[:0:0], f...), nil
+}
+
 // A Bool is a Serializer for a bool.
 type Bool bool
 
@@ -377,10 +492,15 @@ func DeserializeBool(d []byte) (Bool, error) {
 
 // Serialize serializes the object.
 func (b Bool) Serialize() ([]byte, error) {
+	return b.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (b Bool) AppendSerialize(dst []byte) ([]byte, error) {
 	if b {
-		return []byte{1}, nil
+		return append(dst, 1), nil
 	} else {
-		return []byte{0}, nil
+		return append(dst, 0), nil
 	}
 }
 
@@ -389,3 +509,8 @@ func (b Bool) Serialize() ([]byte, error) {
 func (b Bool) SerializerType() string {
 	return "bool"
 }
+
+// Copy returns the object itself.
+func (b Bool) Copy() (interface{}, error) {
+	return b, nil
+}
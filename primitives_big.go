@@ -0,0 +1,121 @@
+package serializer
+
+import (
+	"math/big"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	RegisterTypedDeserializer((*BigInt)(nil).SerializerType(), DeserializeBigInt)
+	RegisterTypedDeserializer((*BigFloat)(nil).SerializerType(), DeserializeBigFloat)
+	RegisterTypedDeserializer((*BigRat)(nil).SerializerType(), DeserializeBigRat)
+}
+
+// BigInt is a Serializer for a big.Int.
+//
+// A *BigInt can be converted to and from a *big.Int:
+//
+//     n := big.NewInt(1337)
+//     data, err := SerializeAny((*BigInt)(n))
+//
+// Values are encoded with GobEncode.
+type BigInt big.Int
+
+// DeserializeBigInt deserializes a BigInt.
+func DeserializeBigInt(d []byte) (*BigInt, error) {
+	var res big.Int
+	if err := res.GobDecode(d); err != nil {
+		return nil, essentials.AddCtx("deserialize big.Int", err)
+	}
+	return (*BigInt)(&res), nil
+}
+
+// Serialize serializes the object.
+func (b *BigInt) Serialize() ([]byte, error) {
+	return (*big.Int)(b).GobEncode()
+}
+
+// SerializerType returns the unique ID used to serialize
+// a BigInt.
+func (b *BigInt) SerializerType() string {
+	return "big.Int"
+}
+
+// Copy creates a copy of the number.
+// A nil *BigInt is copied as nil.
+func (b *BigInt) Copy() (interface{}, error) {
+	if b == nil {
+		return b, nil
+	}
+	return (*BigInt)(new(big.Int).Set((*big.Int)(b))), nil
+}
+
+// BigFloat is a Serializer for a big.Float.
+//
+// Values are encoded with GobEncode, which preserves the
+// precision, rounding mode, and accuracy.
+type BigFloat big.Float
+
+// DeserializeBigFloat deserializes a BigFloat.
+func DeserializeBigFloat(d []byte) (*BigFloat, error) {
+	var res big.Float
+	if err := res.GobDecode(d); err != nil {
+		return nil, essentials.AddCtx("deserialize big.Float", err)
+	}
+	return (*BigFloat)(&res), nil
+}
+
+// Serialize serializes the object.
+func (b *BigFloat) Serialize() ([]byte, error) {
+	return (*big.Float)(b).GobEncode()
+}
+
+// SerializerType returns the unique ID used to serialize
+// a BigFloat.
+func (b *BigFloat) SerializerType() string {
+	return "big.Float"
+}
+
+// Copy creates a copy of the number.
+// A nil *BigFloat is copied as nil.
+func (b *BigFloat) Copy() (interface{}, error) {
+	if b == nil {
+		return b, nil
+	}
+	return (*BigFloat)(new(big.Float).Copy((*big.Float)(b))), nil
+}
+
+// BigRat is a Serializer for a big.Rat.
+//
+// Values are encoded with GobEncode.
+type BigRat big.Rat
+
+// DeserializeBigRat deserializes a BigRat.
+func DeserializeBigRat(d []byte) (*BigRat, error) {
+	var res big.Rat
+	if err := res.GobDecode(d); err != nil {
+		return nil, essentials.AddCtx("deserialize big.Rat", err)
+	}
+	return (*BigRat)(&res), nil
+}
+
+// Serialize serializes the object.
+func (b *BigRat) Serialize() ([]byte, error) {
+	return (*big.Rat)(b).GobEncode()
+}
+
+// SerializerType returns the unique ID used to serialize
+// a BigRat.
+func (b *BigRat) SerializerType() string {
+	return "big.Rat"
+}
+
+// Copy creates a copy of the number.
+// A nil *BigRat is copied as nil.
+func (b *BigRat) Copy() (interface{}, error) {
+	if b == nil {
+		return b, nil
+	}
+	return (*BigRat)(new(big.Rat).Set((*big.Rat)(b))), nil
+}
@@ -0,0 +1,194 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	RegisterTypedDeserializer(Complex64(0).SerializerType(), DeserializeComplex64)
+	RegisterTypedDeserializer(Complex128(0).SerializerType(), DeserializeComplex128)
+	RegisterTypedDeserializer(Complex64Slice(nil).SerializerType(),
+		DeserializeComplex64Slice)
+	RegisterTypedDeserializer(Complex128Slice(nil).SerializerType(),
+		DeserializeComplex128Slice)
+}
+
+// Complex64 is a Serializer for a complex64.
+type Complex64 complex64
+
+// DeserializeComplex64 deserializes a Complex64.
+func DeserializeComplex64(d []byte) (Complex64, error) {
+	buf := bytes.NewBuffer(d)
+	var value complex64
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize complex64", err)
+	}
+	return Complex64(value), nil
+}
+
+// Serialize serializes the object.
+func (c Complex64) Serialize() ([]byte, error) {
+	return c.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (c Complex64) AppendSerialize(dst []byte) ([]byte, error) {
+	return appendComplex64(dst, complex64(c)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Complex64.
+func (c Complex64) SerializerType() string {
+	return "complex64"
+}
+
+// Copy returns the object itself.
+func (c Complex64) Copy() (interface{}, error) {
+	return c, nil
+}
+
+// Complex128 is a Serializer for a complex128.
+type Complex128 complex128
+
+// DeserializeComplex128 deserializes a Complex128.
+func DeserializeComplex128(d []byte) (Complex128, error) {
+	buf := bytes.NewBuffer(d)
+	var value complex128
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize complex128", err)
+	}
+	return Complex128(value), nil
+}
+
+// Serialize serializes the object.
+func (c Complex128) Serialize() ([]byte, error) {
+	return c.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (c Complex128) AppendSerialize(dst []byte) ([]byte, error) {
+	return appendComplex128(dst, complex128(c)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Complex128.
+func (c Complex128) SerializerType() string {
+	return "complex128"
+}
+
+// Copy returns the object itself.
+func (c Complex128) Copy() (interface{}, error) {
+	return c, nil
+}
+
+// A Complex64Slice is a Serializer for a []complex64.
+type Complex64Slice []complex64
+
+// DeserializeComplex64Slice deserializes a Complex64Slice.
+func DeserializeComplex64Slice(d []byte) (Complex64Slice, error) {
+	reader := bytes.NewBuffer(d)
+	var size uint64
+	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+		return nil, essentials.AddCtx("deserialize []complex64", err)
+	}
+	n, err := checkElementCount(size, 8, reader.Len())
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize []complex64", err)
+	}
+	vec := make([]complex64, n)
+	if err := binary.Read(reader, binary.LittleEndian, vec); err != nil {
+		return nil, essentials.AddCtx("deserialize []complex64", err)
+	}
+	return vec, nil
+}
+
+// Serialize serializes the object.
+func (c Complex64Slice) Serialize() ([]byte, error) {
+	return c.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (c Complex64Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+8*len(c))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(c)))
+	for _, x := range c {
+		dst = appendComplex64(dst, x)
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Complex64Slice.
+func (c Complex64Slice) SerializerType() string {
+	return "[]complex64"
+}
+
+// Copy creates a copy of the slice.
+func (c Complex64Slice) Copy() (interface{}, error) {
+	return append(c[:0:0], c...), nil
+}
+
+// A Complex128Slice is a Serializer for a []complex128.
+type Complex128Slice []complex128
+
+// DeserializeComplex128Slice deserializes a Complex128Slice.
+func DeserializeComplex128Slice(d []byte) (Complex128Slice, error) {
+	reader := bytes.NewBuffer(d)
+	var size uint64
+	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+		return nil, essentials.AddCtx("deserialize []complex128", err)
+	}
+	n, err := checkElementCount(size, 16, reader.Len())
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize []complex128", err)
+	}
+	vec := make([]complex128, n)
+	if err := binary.Read(reader, binary.LittleEndian, vec); err != nil {
+		return nil, essentials.AddCtx("deserialize []complex128", err)
+	}
+	return vec, nil
+}
+
+// Serialize serializes the object.
+func (c Complex128Slice) Serialize() ([]byte, error) {
+	return c.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (c Complex128Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+16*len(c))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(c)))
+	for _, x := range c {
+		dst = appendComplex128(dst, x)
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Complex128Slice.
+func (c Complex128Slice) SerializerType() string {
+	return "[]complex128"
+}
+
+// Copy creates a copy of the slice.
+func (c Complex128Slice) Copy() (interface{}, error) {
+	return append(c[:0:0], c...), nil
+}
+
+// appendComplex64 appends the real and imaginary parts of
+// c as little-endian float32 values.
+func appendComplex64(dst []byte, c complex64) []byte {
+	dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(real(c)))
+	return binary.LittleEndian.AppendUint32(dst, math.Float32bits(imag(c)))
+}
+
+// appendComplex128 appends the real and imaginary parts
+// of c as little-endian float64 values.
+func appendComplex128(dst []byte, c complex128) []byte {
+	dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(real(c)))
+	return binary.LittleEndian.AppendUint64(dst, math.Float64bits(imag(c)))
+}
@@ -0,0 +1,540 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	RegisterTypedDeserializer(Int8(0).SerializerType(), DeserializeInt8)
+	RegisterTypedDeserializer(Int16(0).SerializerType(), DeserializeInt16)
+	RegisterTypedDeserializer(Uint(0).SerializerType(), DeserializeUint)
+	RegisterTypedDeserializer(Uint8(0).SerializerType(), DeserializeUint8)
+	RegisterTypedDeserializer(Uint16(0).SerializerType(), DeserializeUint16)
+	RegisterTypedDeserializer(Uint32(0).SerializerType(), DeserializeUint32)
+	RegisterTypedDeserializer(Uint64(0).SerializerType(), DeserializeUint64)
+	RegisterTypedDeserializer(UintSlice(nil).SerializerType(), DeserializeUintSlice)
+}
+
+// Int8 is a Serializer for an int8.
+type Int8 int8
+
+// DeserializeInt8 deserializes an Int8.
+func DeserializeInt8(d []byte) (Int8, error) {
+	buf := bytes.NewBuffer(d)
+	var value int8
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize int8", err)
+	}
+	return Int8(value), nil
+}
+
+// Serialize serializes the object.
+func (i Int8) Serialize() ([]byte, error) {
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int8) AppendSerialize(dst []byte) ([]byte, error) {
+	return append(dst, byte(i)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Int8.
+func (i Int8) SerializerType() string {
+	return "int8"
+}
+
+// Copy returns the object itself.
+func (i Int8) Copy() (interface{}, error) {
+	return i, nil
+}
+
+// Int16 is a Serializer for an int16.
+type Int16 int16
+
+// DeserializeInt16 deserializes an Int16.
+func DeserializeInt16(d []byte) (Int16, error) {
+	buf := bytes.NewBuffer(d)
+	var value int16
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize int16", err)
+	}
+	return Int16(value), nil
+}
+
+// Serialize serializes the object.
+func (i Int16) Serialize() ([]byte, error) {
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int16) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint16(dst, uint16(i)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Int16.
+func (i Int16) SerializerType() string {
+	return "int16"
+}
+
+// Copy returns the object itself.
+func (i Int16) Copy() (interface{}, error) {
+	return i, nil
+}
+
+// Uint is a Serializer for a uint.
+type Uint uint
+
+// DeserializeUint deserializes a Uint.
+// It fails if the value does not fit in a uint, which is
+// possible on 32-bit hosts.
+func DeserializeUint(d []byte) (Uint, error) {
+	buf := bytes.NewBuffer(d)
+	var value uint64
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize uint", err)
+	}
+	if uint64(uint(value)) != value {
+		return 0, essentials.AddCtx("deserialize uint", errUintRange(value))
+	}
+	return Uint(value), nil
+}
+
+// Serialize serializes the object.
+func (u Uint) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u Uint) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint64(dst, uint64(u)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Uint.
+func (u Uint) SerializerType() string {
+	return "uint"
+}
+
+// Copy returns the object itself.
+func (u Uint) Copy() (interface{}, error) {
+	return u, nil
+}
+
+// Uint8 is a Serializer for a uint8.
+type Uint8 uint8
+
+// DeserializeUint8 deserializes a Uint8.
+func DeserializeUint8(d []byte) (Uint8, error) {
+	buf := bytes.NewBuffer(d)
+	var value uint8
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize uint8", err)
+	}
+	return Uint8(value), nil
+}
+
+// Serialize serializes the object.
+func (u Uint8) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u Uint8) AppendSerialize(dst []byte) ([]byte, error) {
+	return append(dst, byte(u)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Uint8.
+func (u Uint8) SerializerType() string {
+	return "uint8"
+}
+
+// Copy returns the object itself.
+func (u Uint8) Copy() (interface{}, error) {
+	return u, nil
+}
+
+// Uint16 is a Serializer for a uint16.
+type Uint16 uint16
+
+// DeserializeUint16 deserializes a Uint16.
+func DeserializeUint16(d []byte) (Uint16, error) {
+	buf := bytes.NewBuffer(d)
+	var value uint16
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize uint16", err)
+	}
+	return Uint16(value), nil
+}
+
+// Serialize serializes the object.
+func (u Uint16) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u Uint16) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint16(dst, uint16(u)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Uint16.
+func (u Uint16) SerializerType() string {
+	return "uint16"
+}
+
+// Copy returns the object itself.
+func (u Uint16) Copy() (interface{}, error) {
+	return u, nil
+}
+
+// Uint32 is a Serializer for a uint32.
+type Uint32 uint32
+
+// DeserializeUint32 deserializes a Uint32.
+func DeserializeUint32(d []byte) (Uint32, error) {
+	buf := bytes.NewBuffer(d)
+	var value uint32
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize uint32", err)
+	}
+	return Uint32(value), nil
+}
+
+// Serialize serializes the object.
+func (u Uint32) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u Uint32) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint32(dst, uint32(u)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Uint32.
+func (u Uint32) SerializerType() string {
+	return "uint32"
+}
+
+// Copy returns the object itself.
+func (u Uint32) Copy() (interface{}, error) {
+	return u, nil
+}
+
+// Uint64 is a Serializer for a uint64.
+type Uint64 uint64
+
+// DeserializeUint64 deserializes a Uint64.
+func DeserializeUint64(d []byte) (Uint64, error) {
+	buf := bytes.NewBuffer(d)
+	var value uint64
+	if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+		return 0, essentials.AddCtx("deserialize uint64", err)
+	}
+	return Uint64(value), nil
+}
+
+// Serialize serializes the object.
+func (u Uint64) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u Uint64) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint64(dst, uint64(u)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Uint64.
+func (u Uint64) SerializerType() string {
+	return "uint64"
+}
+
+// Copy returns the object itself.
+func (u Uint64) Copy() (interface{}, error) {
+	return u, nil
+}
+
+// An Int8Slice is a Serializer for a []int8.
+type Int8Slice []int8
+
+// DeserializeInt8Slice deserializes an Int8Slice.
+func DeserializeInt8Slice(d []byte) (Int8Slice, error) {
+	body, n, err := viewData(d, 1)
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize []int8", err)
+	}
+	vec := make([]int8, n)
+	for j := range vec {
+		vec[j] = int8(body[j])
+	}
+	return vec, nil
+}
+
+// Serialize serializes the object.
+func (i Int8Slice) Serialize() ([]byte, error) {
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int8Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+1*len(i))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(i)))
+	for _, x := range i {
+		dst = append(dst, byte(x))
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Int8Slice.
+func (i Int8Slice) SerializerType() string {
+	return "[]int8"
+}
+
+// Copy creates a copy of the slice.
+func (i Int8Slice) Copy() (interface{}, error) {
+	return append(i[:0:0], i...), nil
+}
+
+// An Int16Slice is a Serializer for a []int16.
+type Int16Slice []int16
+
+// DeserializeInt16Slice deserializes an Int16Slice.
+func DeserializeInt16Slice(d []byte) (Int16Slice, error) {
+	body, n, err := viewData(d, 2)
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize []int16", err)
+	}
+	vec := make([]int16, n)
+	for j := range vec {
+		vec[j] = int16(binary.LittleEndian.Uint16(body[j*2:]))
+	}
+	return vec, nil
+}
+
+// Serialize serializes the object.
+func (i Int16Slice) Serialize() ([]byte, error) {
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i Int16Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+2*len(i))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(i)))
+	for _, x := range i {
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(x))
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Int16Slice.
+func (i Int16Slice) SerializerType() string {
+	return "[]int16"
+}
+
+// Copy creates a copy of the slice.
+func (i Int16Slice) Copy() (interface{}, error) {
+	return append(i[:0:0], i...), nil
+}
+
+// A UintSlice is a Serializer for a []uint.
+type UintSlice []uint
+
+// DeserializeUintSlice deserializes a UintSlice.
+// Like DeserializeUint, it fails if a value does not fit
+// in a uint.
+func DeserializeUintSlice(d []byte) (UintSlice, error) {
+	reader := bytes.NewBuffer(d)
+	var size uint64
+	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+		return nil, essentials.AddCtx("deserialize []uint", err)
+	}
+	n, err := checkElementCount(size, 8, reader.Len())
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize []uint", err)
+	}
+	vec := make([]uint64, n)
+	if err := binary.Read(reader, binary.LittleEndian, vec); err != nil {
+		return nil, essentials.AddCtx("deserialize []uint", err)
+	}
+	res := make(UintSlice, len(vec))
+	for i, x := range vec {
+		if uint64(uint(x)) != x {
+			return nil, essentials.AddCtx("deserialize []uint", errUintRange(x))
+		}
+		res[i] = uint(x)
+	}
+	return res, nil
+}
+
+// Serialize serializes the object.
+func (u UintSlice) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u UintSlice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+8*len(u))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(u)))
+	for _, x := range u {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(x))
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a UintSlice.
+func (u UintSlice) SerializerType() string {
+	return "[]uint"
+}
+
+// Copy creates a copy of the slice.
+func (u UintSlice) Copy() (interface{}, error) {
+	return append(u[:0:0], u...), nil
+}
+
+// A Uint16Slice is a Serializer for a []uint16.
+type Uint16Slice []uint16
+
+// DeserializeUint16Slice deserializes a Uint16Slice.
+func DeserializeUint16Slice(d []byte) (Uint16Slice, error) {
+	body, n, err := viewData(d, 2)
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize []uint16", err)
+	}
+	vec := make([]uint16, n)
+	for j := range vec {
+		vec[j] = binary.LittleEndian.Uint16(body[j*2:])
+	}
+	return vec, nil
+}
+
+// Serialize serializes the object.
+func (u Uint16Slice) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u Uint16Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+2*len(u))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(u)))
+	for _, x := range u {
+		dst = binary.LittleEndian.AppendUint16(dst, x)
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Uint16Slice.
+func (u Uint16Slice) SerializerType() string {
+	return "[]uint16"
+}
+
+// Copy creates a copy of the slice.
+func (u Uint16Slice) Copy() (interface{}, error) {
+	return append(u[:0:0], u...), nil
+}
+
+// A Uint32Slice is a Serializer for a []uint32.
+type Uint32Slice []uint32
+
+// DeserializeUint32Slice deserializes a Uint32Slice.
+func DeserializeUint32Slice(d []byte) (Uint32Slice, error) {
+	body, n, err := viewData(d, 4)
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize []uint32", err)
+	}
+	vec := make([]uint32, n)
+	for j := range vec {
+		vec[j] = binary.LittleEndian.Uint32(body[j*4:])
+	}
+	return vec, nil
+}
+
+// Serialize serializes the object.
+func (u Uint32Slice) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u Uint32Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+4*len(u))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(u)))
+	for _, x := range u {
+		dst = binary.LittleEndian.AppendUint32(dst, x)
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Uint32Slice.
+func (u Uint32Slice) SerializerType() string {
+	return "[]uint32"
+}
+
+// Copy creates a copy of the slice.
+func (u Uint32Slice) Copy() (interface{}, error) {
+	return append(u[:0:0], u...), nil
+}
+
+// A Uint64Slice is a Serializer for a []uint64.
+type Uint64Slice []uint64
+
+// DeserializeUint64Slice deserializes a Uint64Slice.
+func DeserializeUint64Slice(d []byte) (Uint64Slice, error) {
+	body, n, err := viewData(d, 8)
+	if err != nil {
+		return nil, essentials.AddCtx("deserialize []uint64", err)
+	}
+	vec := make([]uint64, n)
+	for j := range vec {
+		vec[j] = binary.LittleEndian.Uint64(body[j*8:])
+	}
+	return vec, nil
+}
+
+// Serialize serializes the object.
+func (u Uint64Slice) Serialize() ([]byte, error) {
+	return u.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (u Uint64Slice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+8*len(u))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(u)))
+	for _, x := range u {
+		dst = binary.LittleEndian.AppendUint64(dst, x)
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Uint64Slice.
+func (u Uint64Slice) SerializerType() string {
+	return "[]uint64"
+}
+
+// Copy creates a copy of the slice.
+func (u Uint64Slice) Copy() (interface{}, error) {
+	return append(u[:0:0], u...), nil
+}
+
+// errUintRange is returned when decoding a uint which
+// does not fit in the host's uint type.
+func errUintRange(value uint64) error {
+	return fmt.Errorf("value %d does not fit in uint", value)
+}
+
+// errIntRange is like errUintRange for int.
+func errIntRange(value int64) error {
+	return fmt.Errorf("value %d does not fit in int", value)
+}
@@ -0,0 +1,359 @@
+package serializer
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	RegisterTypedDeserializer(StringSlice(nil).SerializerType(), DeserializeStringSlice)
+	RegisterTypedDeserializer(BoolSlice(nil).SerializerType(), DeserializeBoolSlice)
+	RegisterTypedDeserializer(Float32Slice2D(nil).SerializerType(),
+		DeserializeFloat32Slice2D)
+	RegisterTypedDeserializer(Float64Slice2D(nil).SerializerType(),
+		DeserializeFloat64Slice2D)
+	RegisterTypedDeserializer(IntSlice2D(nil).SerializerType(), DeserializeIntSlice2D)
+}
+
+// A StringSlice is a Serializer for a []string.
+//
+// Each string is stored with a length prefix.
+type StringSlice []string
+
+// DeserializeStringSlice deserializes a StringSlice.
+func DeserializeStringSlice(d []byte) (res StringSlice, err error) {
+	defer essentials.AddCtxTo("deserialize []string", &err)
+	n, d, err := readCount(d, 8)
+	if err != nil {
+		return nil, err
+	}
+	res = make(StringSlice, n)
+	for i := range res {
+		size, rest, err := readCount(d, 1)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = string(rest[:size])
+		d = rest[size:]
+	}
+	return res, nil
+}
+
+// Serialize serializes the object.
+func (s StringSlice) Serialize() ([]byte, error) {
+	return s.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (s StringSlice) AppendSerialize(dst []byte) ([]byte, error) {
+	size := 8 + 8*len(s)
+	for _, x := range s {
+		size += len(x)
+	}
+	dst = growBytes(dst, size)
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(s)))
+	for _, x := range s {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(len(x)))
+		dst = append(dst, x...)
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a StringSlice.
+func (s StringSlice) SerializerType() string {
+	return "[]string"
+}
+
+// Copy creates a copy of the slice.
+func (s StringSlice) Copy() (interface{}, error) {
+	return append(s[:0:0], s...), nil
+}
+
+// A BoolSlice is a Serializer for a []bool.
+//
+// The values are packed into bits, eight per byte, with
+// the first value in the least significant bit.
+type BoolSlice []bool
+
+// DeserializeBoolSlice deserializes a BoolSlice.
+func DeserializeBoolSlice(d []byte) (res BoolSlice, err error) {
+	defer essentials.AddCtxTo("deserialize []bool", &err)
+	if len(d) < 8 {
+		return nil, ErrBufferUnderflow
+	}
+	n, err := checkElementCount(binary.LittleEndian.Uint64(d), 1, (len(d)-8)*8)
+	if err != nil {
+		return nil, err
+	}
+	bits := d[8 : 8+(n+7)/8]
+	if n%8 != 0 && bits[n/8]>>uint(n%8) != 0 {
+		return nil, errors.New("non-zero padding bits")
+	}
+	res = make(BoolSlice, n)
+	for i := range res {
+		res[i] = bits[i/8]&(1<<uint(i%8)) != 0
+	}
+	return res, nil
+}
+
+// Serialize serializes the object.
+func (b BoolSlice) Serialize() ([]byte, error) {
+	return b.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (b BoolSlice) AppendSerialize(dst []byte) ([]byte, error) {
+	dst = growBytes(dst, 8+(len(b)+7)/8)
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(b)))
+	for i := 0; i < len(b); i += 8 {
+		var packed byte
+		for j := 0; j < 8 && i+j < len(b); j++ {
+			if b[i+j] {
+				packed |= 1 << uint(j)
+			}
+		}
+		dst = append(dst, packed)
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a BoolSlice.
+func (b BoolSlice) SerializerType() string {
+	return "[]bool"
+}
+
+// Copy creates a copy of the slice.
+func (b BoolSlice) Copy() (interface{}, error) {
+	return append(b[:0:0], b...), nil
+}
+
+// A Float32Slice2D is a Serializer for a [][]float32.
+//
+// Rows may have different lengths.
+// Unlike a []Serializer of Float32Slice values, each row
+// is stored with nothing but a length prefix.
+type Float32Slice2D [][]float32
+
+// DeserializeFloat32Slice2D deserializes a Float32Slice2D.
+func DeserializeFloat32Slice2D(d []byte) (res Float32Slice2D, err error) {
+	defer essentials.AddCtxTo("deserialize [][]float32", &err)
+	rows, d, err := readCount(d, 8)
+	if err != nil {
+		return nil, err
+	}
+	res = make(Float32Slice2D, rows)
+	for i := range res {
+		n, rest, err := readCount(d, 4)
+		if err != nil {
+			return nil, err
+		}
+		row := make([]float32, n)
+		for j := range row {
+			row[j] = math.Float32frombits(binary.LittleEndian.Uint32(rest[j*4:]))
+		}
+		res[i] = row
+		d = rest[n*4:]
+	}
+	return res, nil
+}
+
+// Serialize serializes the object.
+func (f Float32Slice2D) Serialize() ([]byte, error) {
+	return f.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (f Float32Slice2D) AppendSerialize(dst []byte) ([]byte, error) {
+	size := 8 + 8*len(f)
+	for _, row := range f {
+		size += 4 * len(row)
+	}
+	dst = growBytes(dst, size)
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(f)))
+	for _, row := range f {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(len(row)))
+		for _, x := range row {
+			dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(x))
+		}
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Float32Slice2D.
+func (f Float32Slice2D) SerializerType() string {
+	return "[][]float32"
+}
+
+// Copy creates a copy of the slice and its rows.
+func (f Float32Slice2D) Copy() (interface{}, error) {
+	if f == nil {
+		return f, nil
+	}
+	res := make(Float32Slice2D, len(f))
+	for i, row := range f {
+		res[i] = append(row[:0:0], row...)
+	}
+	return res, nil
+}
+
+// A Float64Slice2D is a Serializer for a [][]float64.
+// See Float32Slice2D for details.
+type Float64Slice2D [][]float64
+
+// DeserializeFloat64Slice2D deserializes a Float64Slice2D.
+func DeserializeFloat64Slice2D(d []byte) (res Float64Slice2D, err error) {
+	defer essentials.AddCtxTo("deserialize [][]float64", &err)
+	rows, d, err := readCount(d, 8)
+	if err != nil {
+		return nil, err
+	}
+	res = make(Float64Slice2D, rows)
+	for i := range res {
+		n, rest, err := readCount(d, 8)
+		if err != nil {
+			return nil, err
+		}
+		row := make([]float64, n)
+		for j := range row {
+			row[j] = math.Float64frombits(binary.LittleEndian.Uint64(rest[j*8:]))
+		}
+		res[i] = row
+		d = rest[n*8:]
+	}
+	return res, nil
+}
+
+// Serialize serializes the object.
+func (f Float64Slice2D) Serialize() ([]byte, error) {
+	return f.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (f Float64Slice2D) AppendSerialize(dst []byte) ([]byte, error) {
+	size := 8 + 8*len(f)
+	for _, row := range f {
+		size += 8 * len(row)
+	}
+	dst = growBytes(dst, size)
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(f)))
+	for _, row := range f {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(len(row)))
+		for _, x := range row {
+			dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(x))
+		}
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Float64Slice2D.
+func (f Float64Slice2D) SerializerType() string {
+	return "[][]float64"
+}
+
+// Copy creates a copy of the slice and its rows.
+func (f Float64Slice2D) Copy() (interface{}, error) {
+	if f == nil {
+		return f, nil
+	}
+	res := make(Float64Slice2D, len(f))
+	for i, row := range f {
+		res[i] = append(row[:0:0], row...)
+	}
+	return res, nil
+}
+
+// An IntSlice2D is a Serializer for a [][]int.
+// See Float32Slice2D for details.
+//
+// Like IntSlice, the values are stored as 64-bit integers.
+// Decoding fails if a value does not fit in an int, which
+// is possible on 32-bit hosts.
+type IntSlice2D [][]int
+
+// DeserializeIntSlice2D deserializes an IntSlice2D.
+func DeserializeIntSlice2D(d []byte) (res IntSlice2D, err error) {
+	defer essentials.AddCtxTo("deserialize [][]int", &err)
+	rows, d, err := readCount(d, 8)
+	if err != nil {
+		return nil, err
+	}
+	res = make(IntSlice2D, rows)
+	for i := range res {
+		n, rest, err := readCount(d, 8)
+		if err != nil {
+			return nil, err
+		}
+		row := make([]int, n)
+		for j := range row {
+			x := int64(binary.LittleEndian.Uint64(rest[j*8:]))
+			if int64(int(x)) != x {
+				return nil, errIntRange(x)
+			}
+			row[j] = int(x)
+		}
+		res[i] = row
+		d = rest[n*8:]
+	}
+	return res, nil
+}
+
+// Serialize serializes the object.
+func (i IntSlice2D) Serialize() ([]byte, error) {
+	return i.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (i IntSlice2D) AppendSerialize(dst []byte) ([]byte, error) {
+	size := 8 + 8*len(i)
+	for _, row := range i {
+		size += 8 * len(row)
+	}
+	dst = growBytes(dst, size)
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(i)))
+	for _, row := range i {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(len(row)))
+		for _, x := range row {
+			dst = binary.LittleEndian.AppendUint64(dst, uint64(x))
+		}
+	}
+	return dst, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// an IntSlice2D.
+func (i IntSlice2D) SerializerType() string {
+	return "[][]int"
+}
+
+// Copy creates a copy of the slice and its rows.
+func (i IntSlice2D) Copy() (interface{}, error) {
+	if i == nil {
+		return i, nil
+	}
+	res := make(IntSlice2D, len(i))
+	for i, row := range i {
+		res[i] = append(row[:0:0], row...)
+	}
+	return res, nil
+}
+
+// readCount reads a uint64 element count from the start
+// of d and checks it with checkElementCount.
+// It returns the count and the data after it.
+func readCount(d []byte, elemSize int) (int, []byte, error) {
+	if len(d) < 8 {
+		return 0, nil, ErrBufferUnderflow
+	}
+	n, err := checkElementCount(binary.LittleEndian.Uint64(d), elemSize, len(d)-8)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, d[8:], nil
+}
@@ -1,9 +1,13 @@
 package serializer
 
 import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
 	"math/rand"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestPrimitives(t *testing.T) {
@@ -57,6 +61,196 @@ func TestPrimitives(t *testing.T) {
 	}
 }
 
+func TestSizedPrimitives(t *testing.T) {
+	objects := []Serializer{
+		Int8(-128),
+		Int16(-1337),
+		Uint(^uint(0)),
+		Uint8(255),
+		Uint16(65535),
+		Uint32(1 << 31),
+		Uint64(1<<64 - 1),
+		Int8Slice{-1, 0, 127},
+		Int16Slice{},
+		UintSlice{1, 2, 3},
+		Uint16Slice{1, 65535},
+		Uint32Slice{1 << 31},
+		Uint64Slice{1<<64 - 1, 0},
+		Complex64(complex(1.5, -2)),
+		Complex128(complex(-3, 1e100)),
+		Complex64Slice{1, 2i, complex(3, 4)},
+		Complex128Slice{},
+	}
+	testRoundTrip(t, objects)
+
+	large := uint64(1 << 40)
+	data, _ := Uint64(large).Serialize()
+	_, err := DeserializeUint(data)
+	if fits := uint64(uint(large)) == large; fits != (err == nil) {
+		t.Errorf("unexpected error for large uint: %v", err)
+	}
+}
+
+func TestSizedPrimitivesAny(t *testing.T) {
+	data, err := SerializeAny(uint16(7), []uint64{1, 2}, complex64(1i), []int8{-3},
+		uint8(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var x uint16
+	var y []uint64
+	var z complex64
+	var w []int8
+	var v uint8
+	if err := DeserializeAny(data, &x, &y, &z, &w, &v); err != nil {
+		t.Fatal(err)
+	}
+	if x != 7 || !reflect.DeepEqual(y, []uint64{1, 2}) || z != 1i ||
+		!reflect.DeepEqual(w, []int8{-3}) || v != 9 {
+		t.Errorf("unexpected values: %v %v %v %v %v", x, y, z, w, v)
+	}
+}
+
+func TestTimePrimitives(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	times := []time.Time{
+		{},
+		time.Date(2020, 3, 8, 1, 30, 15, 123456789, ny),
+		time.Date(2020, 3, 8, 3, 30, 0, 0, ny),
+		time.Date(1969, 12, 31, 23, 59, 59, 1, time.UTC),
+		time.Date(2001, 1, 1, 0, 0, 0, 0, time.FixedZone("XYZ", 3600+1800)),
+		time.Date(2001, 1, 1, 0, 0, 0, 0, time.Local),
+	}
+	for i, tm := range times {
+		data, err := SerializeAny(tm, 90*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded time.Time
+		var duration time.Duration
+		if err := DeserializeAny(data, &decoded, &duration); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(tm) {
+			t.Errorf("time %d: expected %v but got %v", i, tm, decoded)
+		}
+		_, offset1 := tm.Zone()
+		_, offset2 := decoded.Zone()
+		if offset1 != offset2 {
+			t.Errorf("time %d: expected offset %d but got %d", i, offset1, offset2)
+		}
+		if tm.Location() != time.Local &&
+			decoded.Location().String() != tm.Location().String() {
+			t.Errorf("time %d: expected location %v but got %v", i, tm.Location(),
+				decoded.Location())
+		}
+		if duration != 90*time.Minute {
+			t.Errorf("unexpected duration: %v", duration)
+		}
+	}
+}
+
+func TestBigPrimitives(t *testing.T) {
+	n, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	f := new(big.Float).SetPrec(200).SetMode(big.ToZero)
+	f.SetString("3.14159265358979323846264338327950288419716939937510582097494459")
+	r := big.NewRat(-22, 7)
+
+	data, err := SerializeAny(n, f, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n1 *big.Int
+	var f1 *big.Float
+	var r1 *big.Rat
+	if err := DeserializeAny(data, &n1, &f1, &r1); err != nil {
+		t.Fatal(err)
+	}
+	if n1.Cmp(n) != 0 {
+		t.Errorf("expected %v but got %v", n, n1)
+	}
+	if f1.Cmp(f) != 0 || f1.Prec() != f.Prec() || f1.Mode() != f.Mode() {
+		t.Errorf("expected %v but got %v", f, f1)
+	}
+	if r1.Cmp(r) != 0 {
+		t.Errorf("expected %v but got %v", r, r1)
+	}
+}
+
+func TestSlicePrimitives(t *testing.T) {
+	objects := []Serializer{
+		StringSlice{"", "hello", "world!"},
+		StringSlice{},
+		BoolSlice{true, false, false, true, true, false, true, true, false, true},
+		BoolSlice{true, true, true, true, true, true, true, true},
+		BoolSlice{},
+		Float32Slice2D{{1, 2}, {}, {3.5}},
+		Float64Slice2D{{-1e100}, {1, 2, 3}},
+		Float64Slice2D{},
+		IntSlice2D{{}, {-(1 << 30), 7}},
+	}
+	testRoundTrip(t, objects)
+
+	bools := BoolSlice{true, false, true, true, false, false, false, false, true}
+	packed, _ := bools.Serialize()
+	if !bytes.Equal(packed[8:], []byte{0x0d, 0x01}) {
+		t.Errorf("unexpected packing: %v", packed[8:])
+	}
+	packed[9] |= 0x80
+	if _, err := DeserializeBoolSlice(packed); err == nil {
+		t.Error("expected error for non-zero padding")
+	}
+
+	large := int64(-1 << 40)
+	data, _ := IntSlice2D{{0}}.Serialize()
+	binary.LittleEndian.PutUint64(data[16:], uint64(large))
+	decodedRows, err := DeserializeIntSlice2D(data)
+	if fits := int64(int(large)) == large; fits != (err == nil) {
+		t.Errorf("unexpected error for large int: %v", err)
+	} else if fits && decodedRows[0][0] != int(large) {
+		t.Errorf("unexpected large int: %v", decodedRows)
+	}
+
+	var strs []string
+	var rows [][]float64
+	data, _ = SerializeAny([]string{"a"}, [][]float64{{1}})
+	if err := DeserializeAny(data, &strs, &rows); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(strs, []string{"a"}) ||
+		!reflect.DeepEqual(rows, [][]float64{{1}}) {
+		t.Errorf("unexpected values: %v %v", strs, rows)
+	}
+}
+
+// testRoundTrip checks that objects survive SerializeSlice
+// and DeserializeSlice, and that their deserializers
+// reject truncated data.
+func testRoundTrip(t *testing.T, objects []Serializer) {
+	data, err := SerializeSlice(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DeserializeSlice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, objects) {
+		t.Errorf("expected %v but got %v", objects, decoded)
+	}
+	for _, obj := range objects {
+		data, _ := obj.Serialize()
+		if len(data) > 0 {
+			_, err := DefaultRegistry.Get(obj.SerializerType())(data[:len(data)-1])
+			if err == nil {
+				t.Errorf("%s: expected error for truncated data", obj.SerializerType())
+			}
+		}
+	}
+}
+
 func BenchmarkFloat32Serialize(b *testing.B) {
 	buf := make([]float32, 1000000)
 	for i := range buf {
@@ -122,3 +316,53 @@ func BenchmarkIntDeserialize(b *testing.B) {
 		DeserializeIntSlice(data)
 	}
 }
+
+func TestAppendSerialize(t *testing.T) {
+	objects := []Serializer{
+		Bytes([]byte("hello, world")),
+		String("hello, world"),
+		Int(-1337),
+		IntSlice([]int{1, -2, 3}),
+		Int64(-133713371337),
+		Int32(-13371337),
+		Int64Slice([]int64{1, -2, 133713371337}),
+		Int32Slice([]int32{1, -2, 15}),
+		Float64(3.1415),
+		Float32(3.1415),
+		Float64Slice([]float64{1, 0.5, -5.15e20}),
+		Float32Slice([]float32{-1337, 0.5, 5e10}),
+		Bool(true),
+		Bool(false),
+		slice{Int(3), Float64Slice{1, 2}},
+	}
+	prefix := []byte("prefix")
+	for _, obj := range objects {
+		appender, ok := obj.(Appender)
+		if !ok {
+			t.Errorf("%T is not an Appender", obj)
+			continue
+		}
+		expected, err := obj.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := appender.AppendSerialize(append([]byte{}, prefix...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(actual, append(append([]byte{}, prefix...), expected...)) {
+			t.Errorf("%T: AppendSerialize does not match Serialize", obj)
+		}
+	}
+}
+
+func BenchmarkSerializeNested(b *testing.B) {
+	var obj Serializer = Float64Slice(make([]float64, 100))
+	for i := 0; i < 10; i++ {
+		obj = slice{obj, Int(i)}
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SerializeWithType(obj)
+	}
+}
@@ -0,0 +1,121 @@
+package serializer
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	RegisterTypedDeserializer(Time{}.SerializerType(), DeserializeTime)
+	RegisterTypedDeserializer(Duration(0).SerializerType(), DeserializeDuration)
+}
+
+// Time is a Serializer for a time.Time.
+//
+// The instant, the UTC offset, and the name of the
+// location are stored.
+// When decoding, the location is looked up by name, and a
+// fixed zone with the stored name and offset is used if
+// the lookup fails or disagrees with the stored offset.
+// Times in time.Local are decoded in a fixed zone, since
+// the local time zone may differ between machines.
+//
+// The monotonic clock reading is not stored.
+type Time time.Time
+
+// DeserializeTime deserializes a Time.
+func DeserializeTime(d []byte) (Time, error) {
+	if len(d) < 16 {
+		return Time{}, essentials.AddCtx("deserialize time", ErrBufferUnderflow)
+	}
+	sec := int64(binary.LittleEndian.Uint64(d))
+	nsec := binary.LittleEndian.Uint32(d[8:])
+	offset := int(int32(binary.LittleEndian.Uint32(d[12:])))
+	name := string(d[16:])
+	if nsec >= 1e9 {
+		return Time{}, essentials.AddCtx("deserialize time",
+			errors.New("invalid nanoseconds"))
+	}
+	t := time.Unix(sec, int64(nsec))
+	return Time(t.In(timeLocation(t, name, offset))), nil
+}
+
+// Serialize serializes the object.
+func (t Time) Serialize() ([]byte, error) {
+	return t.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (t Time) AppendSerialize(dst []byte) ([]byte, error) {
+	tm := time.Time(t)
+	_, offset := tm.Zone()
+	name := tm.Location().String()
+	if tm.Location() == time.Local {
+		name = ""
+	}
+	dst = growBytes(dst, 16+len(name))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(tm.Unix()))
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(tm.Nanosecond()))
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(int32(offset)))
+	return append(dst, name...), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Time.
+func (t Time) SerializerType() string {
+	return "time.Time"
+}
+
+// Copy returns the object itself.
+func (t Time) Copy() (interface{}, error) {
+	return t, nil
+}
+
+func timeLocation(t time.Time, name string, offset int) *time.Location {
+	if name == "UTC" && offset == 0 {
+		return time.UTC
+	}
+	if name != "" && name != "Local" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			if _, actual := t.In(loc).Zone(); actual == offset {
+				return loc
+			}
+		}
+	}
+	return time.FixedZone(name, offset)
+}
+
+// Duration is a Serializer for a time.Duration.
+type Duration time.Duration
+
+// DeserializeDuration deserializes a Duration.
+func DeserializeDuration(d []byte) (Duration, error) {
+	if len(d) < 8 {
+		return 0, essentials.AddCtx("deserialize duration", ErrBufferUnderflow)
+	}
+	return Duration(binary.LittleEndian.Uint64(d)), nil
+}
+
+// Serialize serializes the object.
+func (d Duration) Serialize() ([]byte, error) {
+	return d.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (d Duration) AppendSerialize(dst []byte) ([]byte, error) {
+	return binary.LittleEndian.AppendUint64(dst, uint64(d)), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Duration.
+func (d Duration) SerializerType() string {
+	return "time.Duration"
+}
+
+// Copy returns the object itself.
+func (d Duration) Copy() (interface{}, error) {
+	return d, nil
+}
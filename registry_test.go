@@ -0,0 +1,184 @@
+package serializer
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r1 := DefaultRegistry.Clone()
+	r2 := DefaultRegistry.Clone()
+	r1.Register("registryTestType", deserializeDemoType1)
+	r2.Register("registryTestType", deserializeDemoType2)
+
+	payload, _ := (&demoType1{X: 1, Y: 2}).Serialize()
+	obj := &rawTypedData{typeID: "registryTestType", data: payload}
+	data, err := SerializeAny([]Serializer{obj}, Int(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out1 []Serializer
+	var num int
+	if err := r1.DeserializeAny(data, &out1, &num); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out1[0], &demoType1{X: 1, Y: 2}) || num != 3 {
+		t.Errorf("unexpected result: %v %v", out1, num)
+	}
+
+	var out2 []Serializer
+	if err := r2.DeserializeAny(data, &out2, &num); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out2[0].(*demoType2); !ok {
+		t.Errorf("expected *demoType2 but got %T", out2[0])
+	}
+
+	if err := NewRegistry().DeserializeAny(data, &out1, &num); err == nil {
+		t.Error("expected error from empty registry")
+	}
+
+	r1.Update("registryTestType", nil)
+	if r1.Get("registryTestType") != nil {
+		t.Error("deserializer was not removed")
+	}
+}
+
+func TestRegistryBuiltins(t *testing.T) {
+	sliceData, err := SerializeAny([]Serializer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapData, err := SerializeAny(map[string]Serializer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{sliceData, mapData} {
+		if _, err := NewRegistry().DeserializeSlice(data); err == nil {
+			t.Error("expected error from empty registry")
+		}
+	}
+
+	r := DefaultRegistry.Clone()
+	r.Update(slice(nil).SerializerType(), func(d []byte) (Serializer, error) {
+		return String("overridden"), nil
+	})
+	r.Update(Map(nil).SerializerType(), nil)
+	if objs, err := r.DeserializeSlice(sliceData); err != nil {
+		t.Fatal(err)
+	} else if objs[0] != String("overridden") {
+		t.Errorf("override was ignored: %v", objs[0])
+	}
+	if _, err := r.DeserializeSlice(mapData); err == nil {
+		t.Error("expected error for removed type ID")
+	}
+	if _, err := DeserializeSlice(mapData); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegistryAlias(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	r.Register("newType", deserializeDemoType1)
+	r.RegisterAlias("oldType", "newType")
+	r.RegisterAlias("olderType", "oldType")
+
+	data, err := SerializeAny(&demoType1{X: 3}, Int(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte("demoType1"), []byte("olderType"), 1)
+	var obj *demoType1
+	var num int
+	if err := r.DeserializeAny(data, &obj, &num); err != nil {
+		t.Fatal(err)
+	}
+	if obj.X != 3 || num != 5 {
+		t.Errorf("unexpected result: %v %v", obj, num)
+	}
+
+	expected := map[string]string{"olderType": "newType"}
+	if hits := r.AliasHits(); !reflect.DeepEqual(hits, expected) {
+		t.Errorf("expected hits %v but got %v", expected, hits)
+	}
+	r.ResetAliasHits()
+	if len(r.AliasHits()) != 0 {
+		t.Error("hits were not reset")
+	}
+
+	r.RegisterAlias("cycle1", "cycle2")
+	r.RegisterAlias("cycle2", "cycle1")
+	if r.Get("cycle1") != nil {
+		t.Error("expected nil for alias cycle")
+	}
+}
+
+func TestRegistryMigration(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	r.RegisterMigration("counter@v1", "counter@v2", func(d []byte) ([]byte, error) {
+		var x int
+		if err := DeserializeAny(d, &x); err != nil {
+			return nil, err
+		}
+		return SerializeAny(x, "default")
+	})
+	r.RegisterMigration("counter@v2", "counter@v3", func(d []byte) ([]byte, error) {
+		var x int
+		var s string
+		if err := DeserializeAny(d, &x, &s); err != nil {
+			return nil, err
+		}
+		return SerializeAny(s, x*2)
+	})
+	r.Register("counter@v3", func(d []byte) (Serializer, error) {
+		var s string
+		var x int
+		if err := DeserializeAny(d, &s, &x); err != nil {
+			return nil, err
+		}
+		return String(s + ":" + strconv.Itoa(x)), nil
+	})
+
+	payload, err := SerializeAny(21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := SerializeWithType(&rawTypedData{typeID: "counter@v1", data: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := r.DeserializeWithType(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj != String("default:42") {
+		t.Errorf("unexpected result: %v", obj)
+	}
+
+	r.RegisterMigration("loop@v1", "loop@v2", func(d []byte) ([]byte, error) {
+		return d, nil
+	})
+	r.RegisterMigration("loop@v2", "loop@v1", func(d []byte) ([]byte, error) {
+		return d, nil
+	})
+	data, _ = SerializeWithType(&rawTypedData{typeID: "loop@v1"})
+	if _, err := r.DeserializeWithType(data); err == nil {
+		t.Error("expected error for migration cycle")
+	}
+}
+
+type rawTypedData struct {
+	typeID string
+	data   []byte
+}
+
+func (r *rawTypedData) Serialize() ([]byte, error) {
+	return r.data, nil
+}
+
+func (r *rawTypedData) SerializerType() string {
+	return r.typeID
+}
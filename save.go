@@ -0,0 +1,282 @@
+package serializer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/unixpickle/essentials"
+)
+
+// defaultFileMode is the mode used by SaveAny.
+const defaultFileMode os.FileMode = 0755
+
+// SaveOptions configures SaveAnyWithOptions.
+//
+// The zero value is valid and results in the same
+// behavior as SaveAny.
+type SaveOptions struct {
+	// Mode is the permission mode of the file, before the
+	// umask is applied.
+	// If it is 0, 0755 is used.
+	Mode os.FileMode
+
+	// Backup, if true, causes an existing file at the
+	// destination path to be kept at path+".bak".
+	// Any previous backup is replaced.
+	Backup bool
+
+	// Container, if non-nil, causes the objects to be
+	// wrapped in a container with the given info.
+	Container *ContainerInfo
+
+	// Compression, if not CodecNone, causes the objects to
+	// be compressed with the given codec.
+	// Since the codec is recorded in the container header,
+	// this implies a container even if Container is nil.
+	Compression Codec
+
+	// Signer, if non-nil, is used to append a signature to
+	// the file.
+	// The file must then be loaded with LoadAnyWithOptions
+	// and the same Signer (or its public counterpart).
+	Signer Signer
+}
+
+// LoadOptions configures LoadAnyWithOptions.
+type LoadOptions struct {
+	// Signer, if non-nil, is used to verify the signature
+	// at the end of the file before anything is decoded.
+	// If the signature is invalid, ErrInvalidSignature is
+	// returned without added context.
+	Signer Signer
+}
+
+// SaveAnyWithOptions is like SaveAny, but with extra
+// options.
+//
+// The file is written atomically: the data is first
+// written to a temporary file in the same directory, which
+// is synced to disk and then renamed to the destination
+// path.
+// Thus, a crash in the middle of saving leaves either the
+// old file or the new file, but never a partial file.
+//
+// If opts is nil, default options are used.
+func SaveAnyWithOptions(path string, opts *SaveOptions, obj ...interface{}) (err error) {
+	defer essentials.AddCtxTo("SaveAny", &err)
+
+	if opts == nil {
+		opts = &SaveOptions{}
+	}
+	mode := opts.Mode
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+
+	objs := make([]Serializer, len(obj))
+	for i, x := range obj {
+		objs[i], err = toSerializer(x)
+		if err != nil {
+			return err
+		}
+	}
+
+	container := opts.Container
+	if opts.Compression != CodecNone {
+		info := ContainerInfo{}
+		if container != nil {
+			info = *container
+		}
+		info.Compression = opts.Compression
+		container = &info
+	}
+
+	return writeFileAtomic(path, mode, opts.Backup, func(w io.Writer) error {
+		if opts.Signer != nil {
+			var buf bytes.Buffer
+			if err := writeObjects(&buf, container, objs); err != nil {
+				return err
+			}
+			data, err := appendSignature(buf.Bytes(), opts.Signer)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		}
+		return writeObjects(w, container, objs)
+	})
+}
+
+// LoadAnyWithOptions is like LoadAny, but with extra
+// options.
+//
+// If opts is nil, default options are used.
+func LoadAnyWithOptions(path string, opts *LoadOptions, objOut ...interface{}) (err error) {
+	defer func() {
+		if err != ErrInvalidSignature {
+			err = essentials.AddCtx("LoadAny", err)
+		}
+	}()
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if opts != nil && opts.Signer != nil {
+		contents, err = verifySignature(contents, opts.Signer)
+		if err != nil {
+			return err
+		}
+	}
+	if IsContainer(contents) {
+		_, contents, err = ReadContainer(contents)
+		if err != nil {
+			return err
+		}
+	}
+	return DeserializeAny(contents, objOut...)
+}
+
+// writeObjects writes objects as a raw stream or, if the
+// container info is non-nil, as a container.
+func writeObjects(w io.Writer, container *ContainerInfo, objs []Serializer) error {
+	if container != nil {
+		payload, err := SerializeSlice(objs)
+		if err != nil {
+			return err
+		}
+		return WriteContainer(w, container, payload)
+	}
+	enc := NewEncoder(w)
+	for _, x := range objs {
+		if err := enc.Encode(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes a file by writing to a temporary
+// file and then renaming it to the destination.
+func writeFileAtomic(path string, mode os.FileMode, backup bool,
+	write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	f, err := createTemp(dir, "."+filepath.Base(path)+".tmp", mode)
+	if err != nil {
+		return err
+	}
+	tempPath := f.Name()
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tempPath)
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if backup {
+		if err := backupFile(path); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// createTemp creates a new temporary file in dir.
+//
+// Unlike ioutil.TempFile, the file is created with the
+// given mode, which is subject to the umask.
+func createTemp(dir, prefix string, mode os.FileMode) (*os.File, error) {
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, mode)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
+	}
+	return nil, errors.New("create temporary file: too many attempts")
+}
+
+// backupFile links or copies an existing file to
+// path+".bak".
+// It does nothing if the file does not exist.
+//
+// The original file stays in place until it is atomically
+// replaced, so there is never a moment with no file at
+// path.
+func backupFile(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	backupPath := path + ".bak"
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.Link(path, backupPath) == nil {
+		return nil
+	}
+	return copyFile(path, backupPath, info.Mode().Perm())
+}
+
+// copyFile copies the contents of src to a new file at
+// dst.
+func copyFile(src, dst string, mode os.FileMode) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+// syncDir attempts to flush a directory entry to disk.
+// Not every platform supports this, so errors are ignored.
+func syncDir(dir string) {
+	if f, err := os.Open(dir); err == nil {
+		f.Sync()
+		f.Close()
+	}
+}
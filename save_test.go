@@ -0,0 +1,95 @@
+package serializer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAnyWithOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serializer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	opts := &SaveOptions{Mode: 0600, Backup: true}
+	if err := SaveAnyWithOptions(path, opts, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Error("unexpected backup file")
+	}
+	opts.Container = &ContainerInfo{}
+	if err := SaveAnyWithOptions(path, opts, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected mode: %v", info.Mode())
+	}
+
+	var x int
+	if err := LoadAny(path, &x); err != nil {
+		t.Fatal(err)
+	} else if x != 2 {
+		t.Errorf("expected 2 but got %d", x)
+	}
+	if err := LoadAny(path+".bak", &x); err != nil {
+		t.Fatal(err)
+	} else if x != 1 {
+		t.Errorf("expected 1 but got %d", x)
+	}
+
+	if info, err := os.Stat(path + ".bak"); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected backup mode: %v", info.Mode())
+	}
+
+	if err := SaveAny(path, make(chan int)); err == nil {
+		t.Error("expected error")
+	}
+	if err := LoadAny(path, &x); err != nil {
+		t.Fatal(err)
+	} else if x != 2 {
+		t.Errorf("failed save modified the file")
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 files but got %d", len(entries))
+	}
+}
+
+func TestSaveAnyCompression(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serializer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	opts := &SaveOptions{Compression: CodecGzip}
+	if err := SaveAnyWithOptions(path, opts, make([]float64, 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Size() > 1000 {
+		t.Errorf("file was not compressed (%d bytes)", info.Size())
+	}
+	var out []float64
+	if err := LoadAny(path, &out); err != nil {
+		t.Fatal(err)
+	} else if len(out) != 1000 {
+		t.Errorf("unexpected length: %d", len(out))
+	}
+}
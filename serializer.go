@@ -35,3 +35,19 @@ type Serializer interface {
 	// in the decoder table.
 	SerializerType() string
 }
+
+// An Appender is a Serializer which can append its
+// serialized form to an existing buffer.
+//
+// SerializeWithType and SerializeSlice use AppendSerialize
+// when it is available, avoiding intermediate copies when
+// serializing nested objects.
+// AppendSerialize must produce the same bytes as
+// Serialize.
+type Appender interface {
+	Serializer
+
+	// AppendSerialize appends the serialized object to dst
+	// and returns the extended buffer.
+	AppendSerialize(dst []byte) ([]byte, error)
+}
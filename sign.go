@@ -0,0 +1,135 @@
+package serializer
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/unixpickle/essentials"
+)
+
+// ErrInvalidSignature is returned when signed data fails
+// verification.
+//
+// Functions which verify signatures return this error
+// as-is (without added context), so it can be compared
+// directly.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// A Signer signs and verifies data.
+type Signer interface {
+	// Sign produces a signature for the data.
+	Sign(data []byte) ([]byte, error)
+
+	// Verify checks the signature for the data.
+	Verify(data, signature []byte) bool
+
+	// SignatureSize returns the size of every signature
+	// produced by Sign.
+	SignatureSize() int
+}
+
+// HMACSigner is a Signer which uses HMAC-SHA256 with the
+// key stored in the slice.
+type HMACSigner []byte
+
+// Sign produces a signature for the data.
+func (h HMACSigner) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, h)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// Verify checks the signature for the data.
+func (h HMACSigner) Verify(data, signature []byte) bool {
+	expected, _ := h.Sign(data)
+	return hmac.Equal(expected, signature)
+}
+
+// SignatureSize returns the size of an HMAC-SHA256.
+func (h HMACSigner) SignatureSize() int {
+	return sha256.Size
+}
+
+// Ed25519Signer is a Signer which uses ed25519 signatures.
+//
+// Only PublicKey is needed to verify signatures.
+// If PublicKey is nil, it is derived from PrivateKey.
+type Ed25519Signer struct {
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+// Sign produces a signature for the data.
+func (e *Ed25519Signer) Sign(data []byte) ([]byte, error) {
+	if len(e.PrivateKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("sign: invalid ed25519 private key")
+	}
+	return ed25519.Sign(e.PrivateKey, data), nil
+}
+
+// Verify checks the signature for the data.
+func (e *Ed25519Signer) Verify(data, signature []byte) bool {
+	pub := e.PublicKey
+	if pub == nil && len(e.PrivateKey) == ed25519.PrivateKeySize {
+		pub = e.PrivateKey.Public().(ed25519.PublicKey)
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(pub, data, signature)
+}
+
+// SignatureSize returns the size of an ed25519 signature.
+func (e *Ed25519Signer) SignatureSize() int {
+	return ed25519.SignatureSize
+}
+
+// SerializeSigned is like SerializeWithType, but it
+// appends a signature to the data.
+// This is meant to be used with DeserializeSigned.
+func SerializeSigned(s Serializer, signer Signer) ([]byte, error) {
+	data, err := SerializeWithType(s)
+	if err != nil {
+		return nil, err
+	}
+	return appendSignature(data, signer)
+}
+
+// DeserializeSigned performs the inverse of
+// SerializeSigned.
+//
+// The signature is checked before the data is decoded, so
+// no Deserializer is invoked on data which fails
+// verification.
+// In this case, ErrInvalidSignature is returned.
+func DeserializeSigned(d []byte, signer Signer) (Serializer, error) {
+	data, err := verifySignature(d, signer)
+	if err != nil {
+		return nil, err
+	}
+	return DeserializeWithType(data)
+}
+
+func appendSignature(data []byte, signer Signer) ([]byte, error) {
+	sig, err := signer.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, sig...), nil
+}
+
+// verifySignature checks the signature at the end of the
+// data and returns the data without it.
+func verifySignature(d []byte, signer Signer) ([]byte, error) {
+	size := signer.SignatureSize()
+	if len(d) < size {
+		return nil, essentials.AddCtx("verify signature", ErrBufferUnderflow)
+	}
+	data, sig := d[:len(d)-size], d[len(d)-size:]
+	if !signer.Verify(data, sig) {
+		return nil, ErrInvalidSignature
+	}
+	return data, nil
+}
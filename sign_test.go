@@ -0,0 +1,78 @@
+package serializer
+
+import (
+	"crypto/ed25519"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSerializeSigned(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := []Signer{
+		HMACSigner("secret key"),
+		&Ed25519Signer{PrivateKey: priv},
+	}
+	verifiers := []Signer{
+		HMACSigner("secret key"),
+		&Ed25519Signer{PublicKey: pub},
+	}
+	for i, signer := range signers {
+		data, err := SerializeSigned(String("hello"), signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj, err := DeserializeSigned(data, verifiers[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if obj != String("hello") {
+			t.Errorf("unexpected result: %v", obj)
+		}
+		for j := range data {
+			corrupted := append([]byte{}, data...)
+			corrupted[j] ^= 1
+			if _, err := DeserializeSigned(corrupted, verifiers[i]); err != ErrInvalidSignature {
+				t.Errorf("signer %d byte %d: expected ErrInvalidSignature but got %v",
+					i, j, err)
+			}
+		}
+	}
+	if _, err := (&Ed25519Signer{PublicKey: pub}).Sign(nil); err == nil {
+		t.Error("expected error when signing without private key")
+	}
+}
+
+func TestLoadAnySigned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serializer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	signer := HMACSigner("key")
+	saveOpts := &SaveOptions{Signer: signer, Compression: CodecGzip}
+	if err := SaveAnyWithOptions(path, saveOpts, 3, "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	var x int
+	var s string
+	loadOpts := &LoadOptions{Signer: signer}
+	if err := LoadAnyWithOptions(path, loadOpts, &x, &s); err != nil {
+		t.Fatal(err)
+	}
+	if x != 3 || s != "hello" {
+		t.Errorf("unexpected result: %d %s", x, s)
+	}
+
+	loadOpts.Signer = HMACSigner("wrong key")
+	if err := LoadAnyWithOptions(path, loadOpts, &x, &s); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature but got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package serializer
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/unixpickle/essentials"
+)
+
+// maxEncoderBuffer is the largest buffer an Encoder keeps
+// between calls to Encode.
+const maxEncoderBuffer = 1 << 20
+
+// An Encoder writes objects to an io.Writer one at a
+// time.
+//
+// The resulting stream is byte-for-byte identical to the
+// output of SerializeSlice (or SerializeAny) on the same
+// objects, so it can be read back with DeserializeSlice,
+// DeserializeAny, LoadAny, or a Decoder.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder creates an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes an object to the stream.
+//
+// The object may be a Serializer or any built-in type
+// supported by SerializeAny.
+func (e *Encoder) Encode(obj interface{}) (err error) {
+	defer essentials.AddCtxTo("encode", &err)
+	s, err := toSerializer(obj)
+	if err != nil {
+		return err
+	}
+
+	// Reuse the buffer between calls to avoid allocations.
+	e.buf, err = AppendSlice(e.buf[:0], []Serializer{s})
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(e.buf)
+	if cap(e.buf) > maxEncoderBuffer {
+		e.buf = nil
+	}
+	return err
+}
+
+// A Decoder reads objects from an io.Reader one at a
+// time.
+//
+// A Decoder can read streams produced by an Encoder,
+// SerializeSlice, SerializeAny, or SaveAny.
+type Decoder struct {
+	// Registry is used to look up type IDs.
+	// If it is nil, DefaultRegistry is used.
+	Registry *Registry
+
+	r io.Reader
+}
+
+// NewDecoder creates a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next object from the stream.
+//
+// At the end of the stream, this returns io.EOF.
+// If the stream ends in the middle of an object, this
+// returns ErrBufferUnderflow.
+func (d *Decoder) Decode() (obj Serializer, err error) {
+	var sizeBuf [8]byte
+	if _, err := io.ReadFull(d.r, sizeBuf[:]); err == io.EOF {
+		return nil, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, essentials.AddCtx("decode", ErrBufferUnderflow)
+	} else if err != nil {
+		return nil, essentials.AddCtx("decode", err)
+	}
+	registry := d.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	size := helperByteOrder.Uint64(sizeBuf[:])
+	if err := registry.GetDecodeLimits().checkBytes(size); err != nil {
+		return nil, essentials.AddCtx("decode", err)
+	}
+
+	// Grow the buffer as data arrives so that a corrupt size
+	// cannot trigger a huge allocation.
+	var data bytes.Buffer
+	if n, err := io.CopyN(&data, d.r, int64(size)); err != nil && err != io.EOF {
+		return nil, essentials.AddCtx("decode", err)
+	} else if uint64(n) != size {
+		return nil, essentials.AddCtx("decode", ErrBufferUnderflow)
+	}
+
+	obj, err = registry.DeserializeWithType(data.Bytes())
+	if err != nil {
+		return nil, essentials.AddCtx("decode", err)
+	}
+	return obj, nil
+}
+
+// DecodeInto reads the next object from the stream and
+// stores it in the variable pointed to by out.
+//
+// Like with DeserializeAny, the object is converted to
+// the desired type if necessary.
+func (d *Decoder) DecodeInto(out interface{}) error {
+	obj, err := d.Decode()
+	if err != nil {
+		return err
+	}
+	if err := assignDecoded(obj, out); err != nil {
+		return essentials.AddCtx("decode", err)
+	}
+	return nil
+}
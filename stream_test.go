@@ -0,0 +1,97 @@
+package serializer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	objects := []interface{}{
+		Int(15),
+		"hello",
+		[]float32{1, 2, 3},
+		[]Serializer{Int(3), Bool(true)},
+		Float64(3.14),
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, obj := range objects {
+		if err := enc.Encode(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := SerializeAny(objects...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatal("stream does not match SerializeAny")
+	}
+
+	dec := NewDecoder(bytes.NewReader(expected))
+	var out1 int
+	if err := dec.DecodeInto(&out1); err != nil {
+		t.Fatal(err)
+	} else if out1 != 15 {
+		t.Errorf("expected 15 but got %d", out1)
+	}
+	for i, x := range objects[1:] {
+		obj, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := toSerializer(x)
+		if !reflect.DeepEqual(obj, expected) {
+			t.Errorf("object %d: expected %v but got %v", i+1, expected, obj)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected EOF but got %v", err)
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	data, err := SerializeAny(Int(15), String("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	boundary, _ := SerializeAny(Int(15))
+	for i := 1; i < len(data); i++ {
+		if i == len(boundary) {
+			continue
+		}
+		dec := NewDecoder(bytes.NewReader(data[:i]))
+		var err error
+		for err == nil {
+			_, err = dec.Decode()
+		}
+		if !errors.Is(err, ErrBufferUnderflow) {
+			t.Errorf("truncation at %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestDecoderReadError(t *testing.T) {
+	data, err := SerializeAny(Int(15), String("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readErr := errors.New("read failed")
+	for i := 1; i < len(data); i++ {
+		r := io.MultiReader(bytes.NewReader(data[:i]), iotest.ErrReader(readErr))
+		dec := NewDecoder(r)
+		var err error
+		for err == nil {
+			_, err = dec.Decode()
+		}
+		if !errors.Is(err, readErr) || errors.Is(err, ErrBufferUnderflow) {
+			t.Errorf("error at %d: unexpected error: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package serializer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/unixpickle/essentials"
+)
+
+// SerializeStruct serializes the exported fields of a
+// struct (or a pointer to a struct) automatically.
+//
+// Each field is encoded with the same conversions as
+// SerializeAny, so fields must be Serializers, built-in
+// types supported by SerializeAny, or interfaces holding
+// Serializers.
+// Fields holding nil pointers or nil interfaces are
+// omitted and will be left as nil by DeserializeStruct.
+//
+// Fields can be configured with struct tags:
+//
+//     Field1 int `serializer:"name"` // stored as "name"
+//     Field2 int `serializer:"-"`    // not stored
+//
+// Fields are stored by name rather than by position, so
+// adding, removing, or reordering fields does not break
+// previously serialized data.
+func SerializeStruct(obj interface{}) (data []byte, err error) {
+	defer essentials.AddCtxTo("serialize struct", &err)
+
+	val := reflect.ValueOf(obj)
+	for val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct but got %T", obj)
+	}
+
+	var fields []interface{}
+	for _, field := range structFields(val.Type()) {
+		fieldVal := val.FieldByIndex(field.Index)
+		switch fieldVal.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			if fieldVal.IsNil() {
+				continue
+			}
+		}
+		s, err := toSerializer(fieldVal.Interface())
+		if err != nil {
+			return nil, essentials.AddCtx("field "+field.Name, err)
+		}
+		fields = append(fields, String(field.Name), s)
+	}
+
+	return SerializeAny(fields...)
+}
+
+// DeserializeStruct reverses SerializeStruct, storing the
+// decoded fields in the struct pointed to by out.
+//
+// Stored fields which do not correspond to a field of the
+// struct are ignored.
+// Fields of the struct which are not stored are left
+// untouched.
+//
+// Type IDs are looked up in the DefaultRegistry.
+func DeserializeStruct(d []byte, out interface{}) error {
+	return deserializeStruct(d, out, &DecodeContext{registry: DefaultRegistry})
+}
+
+// DeserializeStruct is like the global DeserializeStruct,
+// but it decodes the fields as nested objects in c.
+func (c *DecodeContext) DeserializeStruct(d []byte, out interface{}) error {
+	return deserializeStruct(d, out, c)
+}
+
+func deserializeStruct(d []byte, out interface{}, ctx *DecodeContext) (err error) {
+	defer essentials.AddCtxTo("deserialize struct", &err)
+
+	val := reflect.ValueOf(out)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected pointer to struct but got %T", out)
+	}
+	val = val.Elem()
+
+	fieldIndices := map[string][]int{}
+	for _, field := range structFields(val.Type()) {
+		fieldIndices[field.Name] = field.Index
+	}
+
+	objs, err := ctx.DeserializeSlice(d)
+	if err != nil {
+		return err
+	}
+	if len(objs)%2 != 0 {
+		return fmt.Errorf("odd number of objects: %d", len(objs))
+	}
+	for i := 0; i < len(objs); i += 2 {
+		name, ok := objs[i].(String)
+		if !ok {
+			return fmt.Errorf("expected field name but got %T", objs[i])
+		}
+		index, ok := fieldIndices[string(name)]
+		if !ok {
+			continue
+		}
+		fieldPtr := val.FieldByIndex(index).Addr().Interface()
+		if err := assignDecoded(objs[i+1], fieldPtr); err != nil {
+			return essentials.AddCtx("field "+string(name), err)
+		}
+	}
+
+	return nil
+}
+
+// RegisterStruct registers a Deserializer for a struct
+// type that uses SerializeStruct in its Serialize method.
+//
+// The obj argument should be a pointer to the struct, and
+// it is used only to determine the type and type ID.
+// The registered ContextDeserializer produces pointers to
+// new instances of the struct, decoding the fields with
+// the Registry in use.
+//
+// For example:
+//
+//     type MyStruct struct {
+//         X int
+//         A ActivationFunc
+//     }
+//
+//     func (m *MyStruct) Serialize() ([]byte, error) {
+//         return serializer.SerializeStruct(m)
+//     }
+//
+//     func (m *MyStruct) SerializerType() string {
+//         return "github.com/me/pkg.MyStruct"
+//     }
+//
+//     func init() {
+//         serializer.RegisterStruct(&MyStruct{})
+//     }
+func RegisterStruct(obj Serializer) {
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("expected pointer to struct but got %T", obj))
+	}
+	structType := t.Elem()
+	RegisterContextDeserializer(obj.SerializerType(), func(d []byte,
+		ctx *DecodeContext) (Serializer, error) {
+		res := reflect.New(structType)
+		if err := deserializeStruct(d, res.Interface(), ctx); err != nil {
+			return nil, err
+		}
+		return res.Interface().(Serializer), nil
+	})
+}
+
+// structField is an exported field of a struct, named
+// according to its serializer tag.
+type structField struct {
+	Name  string
+	Index []int
+}
+
+func structFields(t reflect.Type) []structField {
+	var res []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("serializer"); ok {
+			tag = strings.Split(tag, ",")[0]
+			if tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+		}
+		res = append(res, structField{Name: name, Index: field.Index})
+	}
+	return res
+}
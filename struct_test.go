@@ -0,0 +1,94 @@
+package serializer
+
+import (
+	"reflect"
+	"testing"
+)
+
+type structTestType struct {
+	Num     int
+	Floats  []float64
+	Name    string `serializer:"name"`
+	Skipped int    `serializer:"-"`
+	Value   Serializer
+	Nil     Serializer
+	Slice   []Serializer
+
+	unexported int
+}
+
+func (s *structTestType) Serialize() ([]byte, error) {
+	return SerializeStruct(s)
+}
+
+func (s *structTestType) SerializerType() string {
+	return "github.com/unixpickle/serializer.structTestType"
+}
+
+func init() {
+	RegisterStruct(&structTestType{})
+}
+
+func TestSerializeStruct(t *testing.T) {
+	obj := &structTestType{
+		Num:        15,
+		Floats:     []float64{1, 2.5},
+		Name:       "hello",
+		Skipped:    3,
+		Value:      Float32(3.5),
+		Slice:      []Serializer{Int(3), &structTestType{Num: 7}},
+		unexported: 5,
+	}
+	data, err := SerializeAny(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded *structTestType
+	if err := DeserializeAny(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := *obj
+	expected.Skipped = 0
+	expected.unexported = 0
+	if !reflect.DeepEqual(*decoded, expected) {
+		t.Errorf("expected %v but got %v", expected, *decoded)
+	}
+}
+
+func TestDeserializeStructRegistry(t *testing.T) {
+	payload, _ := (&demoType1{X: 3}).Serialize()
+	obj := &structTestType{
+		Slice: []Serializer{&rawTypedData{typeID: "structTestNested", data: payload}},
+	}
+	data, err := SerializeWithType(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DeserializeWithType(data); err == nil {
+		t.Error("expected error from DefaultRegistry")
+	}
+	r := DefaultRegistry.Clone()
+	r.Register("structTestNested", deserializeDemoType1)
+	decoded, err := r.DeserializeWithType(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := decoded.(*structTestType).Slice[0]
+	if !reflect.DeepEqual(nested, &demoType1{X: 3}) {
+		t.Errorf("unexpected nested object: %v", nested)
+	}
+}
+
+func TestDeserializeStructTags(t *testing.T) {
+	data, err := SerializeAny("name", "hello", "Skipped", 3, "Unknown", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded structTestType
+	if err := DeserializeStruct(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Name != "hello" || decoded.Skipped != 0 {
+		t.Errorf("unexpected result: %v", decoded)
+	}
+}
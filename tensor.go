@@ -0,0 +1,558 @@
+package serializer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/essentials"
+)
+
+func init() {
+	RegisterTypedDeserializer((*Tensor)(nil).SerializerType(), DeserializeTensor)
+}
+
+// A DType is the element type of a Tensor.
+type DType uint8
+
+const (
+	DTypeFloat32 DType = iota
+	DTypeFloat64
+	DTypeInt32
+	DTypeInt64
+	DTypeUint8
+	DTypeFloat16
+)
+
+// String returns the name of the data type.
+func (d DType) String() string {
+	switch d {
+	case DTypeFloat32:
+		return "float32"
+	case DTypeFloat64:
+		return "float64"
+	case DTypeInt32:
+		return "int32"
+	case DTypeInt64:
+		return "int64"
+	case DTypeUint8:
+		return "uint8"
+	case DTypeFloat16:
+		return "float16"
+	default:
+		return fmt.Sprintf("DType(%d)", int(d))
+	}
+}
+
+// Size returns the number of bytes per element, or 0 for
+// an unknown data type.
+func (d DType) Size() int {
+	switch d {
+	case DTypeUint8:
+		return 1
+	case DTypeFloat16:
+		return 2
+	case DTypeFloat32, DTypeInt32:
+		return 4
+	case DTypeFloat64, DTypeInt64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// A Tensor is a Serializer for a multi-dimensional array.
+//
+// The elements are stored in Data in little-endian order.
+// The element at index (i0, i1, ...) is stored at element
+// position
+//
+//     Offset + i0*Strides[0] + i1*Strides[1] + ...
+//
+// of Data, where positions are measured in elements.
+// If Strides is nil, the tensor is contiguous and stored
+// in row-major order.
+// A nil Shape indicates a scalar.
+//
+// Views created by Slice and Transpose share Data with the
+// original tensor.
+// A tensor is always serialized in contiguous form, so a
+// deserialized tensor has nil Strides and zero Offset.
+type Tensor struct {
+	DType   DType
+	Shape   []int
+	Strides []int
+	Offset  int
+	Data    []byte
+}
+
+// NewTensor creates a zero tensor with the given shape.
+func NewTensor(dtype DType, shape ...int) *Tensor {
+	if dtype.Size() == 0 {
+		panic("unknown dtype: " + dtype.String())
+	}
+	res := &Tensor{DType: dtype, Shape: append([]int{}, shape...)}
+	res.Data = make([]byte, res.NumElements()*dtype.Size())
+	return res
+}
+
+// TensorFromFloat32Slice creates a float32 tensor with the
+// given shape and a copy of the given data.
+//
+// If no shape is given, the tensor is one-dimensional.
+func TensorFromFloat32Slice(data Float32Slice, shape ...int) (*Tensor, error) {
+	res, err := newTensorFromSlice(DTypeFloat32, len(data), shape)
+	if err != nil {
+		return nil, err
+	}
+	for i, x := range data {
+		binary.LittleEndian.PutUint32(res.Data[i*4:], math.Float32bits(x))
+	}
+	return res, nil
+}
+
+// TensorFromFloat64Slice creates a float64 tensor with the
+// given shape and a copy of the given data.
+//
+// If no shape is given, the tensor is one-dimensional.
+func TensorFromFloat64Slice(data Float64Slice, shape ...int) (*Tensor, error) {
+	res, err := newTensorFromSlice(DTypeFloat64, len(data), shape)
+	if err != nil {
+		return nil, err
+	}
+	for i, x := range data {
+		binary.LittleEndian.PutUint64(res.Data[i*8:], math.Float64bits(x))
+	}
+	return res, nil
+}
+
+func newTensorFromSlice(dtype DType, n int, shape []int) (*Tensor, error) {
+	if len(shape) == 0 {
+		shape = []int{n}
+	}
+	res := NewTensor(dtype, shape...)
+	if res.NumElements() != n {
+		return nil, fmt.Errorf("shape %v does not match %d elements", shape, n)
+	}
+	return res, nil
+}
+
+// DeserializeTensor deserializes a Tensor.
+func DeserializeTensor(d []byte) (res *Tensor, err error) {
+	defer essentials.AddCtxTo("deserialize tensor", &err)
+	if len(d) < 1 {
+		return nil, ErrBufferUnderflow
+	}
+	dtype := DType(d[0])
+	if dtype.Size() == 0 {
+		return nil, errors.New("unknown dtype: " + dtype.String())
+	}
+	ndim, d, err := readCount(d[1:], 8)
+	if err != nil {
+		return nil, err
+	}
+	var shape []int
+	if ndim > 0 {
+		shape = make([]int, ndim)
+	}
+	maxElements := (len(d) - ndim*8) / dtype.Size()
+	total := 1
+	for i := range shape {
+		dim := binary.LittleEndian.Uint64(d[i*8:])
+		if dim > math.MaxInt {
+			return nil, fmt.Errorf("invalid dimension: %d", dim)
+		} else if dim != 0 && uint64(total) > uint64(maxElements)/dim {
+			return nil, ErrBufferUnderflow
+		}
+		shape[i] = int(dim)
+		total *= shape[i]
+	}
+	data := d[ndim*8:]
+	if len(data) != total*dtype.Size() {
+		return nil, ErrResidualData
+	}
+	return &Tensor{DType: dtype, Shape: shape, Data: data}, nil
+}
+
+// Serialize serializes the object.
+func (t *Tensor) Serialize() ([]byte, error) {
+	return t.AppendSerialize(nil)
+}
+
+// AppendSerialize appends the serialized object to dst.
+func (t *Tensor) AppendSerialize(dst []byte) ([]byte, error) {
+	if err := t.validate(); err != nil {
+		return nil, essentials.AddCtx("serialize tensor", err)
+	}
+	c := t.Contiguous()
+	dst = growBytes(dst, 9+8*len(c.Shape)+len(c.Data))
+	dst = append(dst, byte(c.DType))
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(c.Shape)))
+	for _, dim := range c.Shape {
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(dim))
+	}
+	return append(dst, c.Data...), nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Tensor.
+func (t *Tensor) SerializerType() string {
+	return "tensor"
+}
+
+// Copy creates a contiguous copy of the tensor which does
+// not share memory with t.
+// A nil *Tensor is copied as nil.
+func (t *Tensor) Copy() (interface{}, error) {
+	if t == nil {
+		return t, nil
+	}
+	c := t.Contiguous()
+	if c == t {
+		c = &Tensor{
+			DType:   t.DType,
+			Shape:   append(t.Shape[:0:0], t.Shape...),
+			Strides: append(t.Strides[:0:0], t.Strides...),
+			Data:    append(t.Data[:0:0], t.Data...),
+		}
+	}
+	return c, nil
+}
+
+// NumElements returns the number of elements in the
+// tensor, which is the product of the shape.
+func (t *Tensor) NumElements() int {
+	res := 1
+	for _, dim := range t.Shape {
+		res *= dim
+	}
+	return res
+}
+
+// IsContiguous checks if the elements are stored in Data
+// in row-major order with no gaps or extra elements.
+func (t *Tensor) IsContiguous() bool {
+	if t.Offset != 0 || len(t.Data) != t.NumElements()*t.DType.Size() {
+		return false
+	}
+	if t.Strides == nil {
+		return true
+	}
+	expected := 1
+	for i := len(t.Shape) - 1; i >= 0; i-- {
+		if t.Shape[i] != 1 && t.Strides[i] != expected {
+			return false
+		}
+		expected *= t.Shape[i]
+	}
+	return true
+}
+
+// Contiguous returns a contiguous tensor with the same
+// elements as t.
+//
+// If t is already contiguous, it is returned directly.
+// Otherwise, the elements are copied.
+func (t *Tensor) Contiguous() *Tensor {
+	if t.IsContiguous() {
+		return t
+	}
+	size := t.DType.Size()
+	res := NewTensor(t.DType, t.Shape...)
+	i := 0
+	t.iterate(func(pos int) {
+		copy(res.Data[i*size:(i+1)*size], t.Data[pos*size:])
+		i++
+	})
+	return res
+}
+
+// Slice creates a view of the elements in the range
+// [start, end) along the given axis.
+func (t *Tensor) Slice(axis, start, end int) *Tensor {
+	if axis < 0 || axis >= len(t.Shape) {
+		panic(fmt.Sprintf("axis %d out of range for %d dimensions", axis, len(t.Shape)))
+	}
+	if start < 0 || end < start || end > t.Shape[axis] {
+		panic(fmt.Sprintf("slice [%d:%d] out of range for dimension %d", start, end,
+			t.Shape[axis]))
+	}
+	strides := t.strides()
+	res := &Tensor{
+		DType:   t.DType,
+		Shape:   append([]int{}, t.Shape...),
+		Strides: strides,
+		Offset:  t.Offset + start*strides[axis],
+		Data:    t.Data,
+	}
+	res.Shape[axis] = end - start
+	return res
+}
+
+// Transpose creates a view with permuted axes, such that
+// axis i of the result is axis perm[i] of t.
+//
+// If perm is empty, the order of the axes is reversed.
+func (t *Tensor) Transpose(perm ...int) *Tensor {
+	if len(perm) == 0 {
+		perm = make([]int, len(t.Shape))
+		for i := range perm {
+			perm[i] = len(perm) - (i + 1)
+		}
+	}
+	if len(perm) != len(t.Shape) {
+		panic(fmt.Sprintf("permutation %v does not match %d dimensions", perm,
+			len(t.Shape)))
+	}
+	strides := t.strides()
+	res := &Tensor{
+		DType:   t.DType,
+		Shape:   make([]int, len(perm)),
+		Strides: make([]int, len(perm)),
+		Offset:  t.Offset,
+		Data:    t.Data,
+	}
+	used := make([]bool, len(perm))
+	for i, axis := range perm {
+		if axis < 0 || axis >= len(perm) || used[axis] {
+			panic(fmt.Sprintf("invalid permutation: %v", perm))
+		}
+		used[axis] = true
+		res.Shape[i] = t.Shape[axis]
+		res.Strides[i] = strides[axis]
+	}
+	return res
+}
+
+// At returns the element at the given index, converted to
+// a float64.
+func (t *Tensor) At(index ...int) float64 {
+	return t.get(t.position(index))
+}
+
+// Set sets the element at the given index, converting the
+// value to the tensor's data type.
+func (t *Tensor) Set(value float64, index ...int) {
+	t.set(t.position(index), value)
+}
+
+// Convert creates a contiguous copy of the tensor with a
+// different data type.
+func (t *Tensor) Convert(dtype DType) *Tensor {
+	res := NewTensor(dtype, t.Shape...)
+	i := 0
+	t.iterate(func(pos int) {
+		res.set(i, t.get(pos))
+		i++
+	})
+	return res
+}
+
+// Float32Slice returns the elements of the tensor in
+// row-major order, converted to float32.
+func (t *Tensor) Float32Slice() Float32Slice {
+	res := make(Float32Slice, 0, t.NumElements())
+	t.iterate(func(pos int) {
+		res = append(res, float32(t.get(pos)))
+	})
+	return res
+}
+
+// Float64Slice returns the elements of the tensor in
+// row-major order, converted to float64.
+func (t *Tensor) Float64Slice() Float64Slice {
+	res := make(Float64Slice, 0, t.NumElements())
+	t.iterate(func(pos int) {
+		res = append(res, t.get(pos))
+	})
+	return res
+}
+
+func (t *Tensor) strides() []int {
+	if t.Strides != nil {
+		return append([]int{}, t.Strides...)
+	}
+	res := make([]int, len(t.Shape))
+	stride := 1
+	for i := len(t.Shape) - 1; i >= 0; i-- {
+		res[i] = stride
+		stride *= t.Shape[i]
+	}
+	return res
+}
+
+func (t *Tensor) position(index []int) int {
+	if len(index) != len(t.Shape) {
+		panic(fmt.Sprintf("index %v does not match %d dimensions", index, len(t.Shape)))
+	}
+	strides := t.strides()
+	pos := t.Offset
+	for i, idx := range index {
+		if idx < 0 || idx >= t.Shape[i] {
+			panic(fmt.Sprintf("index %v out of range for shape %v", index, t.Shape))
+		}
+		pos += idx * strides[i]
+	}
+	return pos
+}
+
+// iterate calls f with the position of every element in
+// row-major order.
+func (t *Tensor) iterate(f func(pos int)) {
+	if t.NumElements() == 0 {
+		return
+	}
+	strides := t.strides()
+	index := make([]int, len(t.Shape))
+	pos := t.Offset
+	for {
+		f(pos)
+		axis := len(index) - 1
+		for ; axis >= 0; axis-- {
+			index[axis]++
+			pos += strides[axis]
+			if index[axis] < t.Shape[axis] {
+				break
+			}
+			pos -= index[axis] * strides[axis]
+			index[axis] = 0
+		}
+		if axis < 0 {
+			return
+		}
+	}
+}
+
+// validate checks that every element of the tensor lies
+// within Data.
+func (t *Tensor) validate() error {
+	size := t.DType.Size()
+	if size == 0 {
+		return errors.New("unknown dtype: " + t.DType.String())
+	}
+	if t.Strides != nil && len(t.Strides) != len(t.Shape) {
+		return errors.New("strides do not match shape")
+	}
+	for _, dim := range t.Shape {
+		if dim < 0 {
+			return fmt.Errorf("invalid shape: %v", t.Shape)
+		}
+	}
+	if t.NumElements() == 0 {
+		return nil
+	}
+	low, high := t.Offset, t.Offset
+	for i, stride := range t.strides() {
+		if stride < 0 {
+			low += stride * (t.Shape[i] - 1)
+		} else {
+			high += stride * (t.Shape[i] - 1)
+		}
+	}
+	if low < 0 || (high+1)*size > len(t.Data) {
+		return errors.New("elements out of bounds")
+	}
+	return nil
+}
+
+func (t *Tensor) get(pos int) float64 {
+	switch t.DType {
+	case DTypeFloat32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(t.Data[pos*4:])))
+	case DTypeFloat64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(t.Data[pos*8:]))
+	case DTypeInt32:
+		return float64(int32(binary.LittleEndian.Uint32(t.Data[pos*4:])))
+	case DTypeInt64:
+		return float64(int64(binary.LittleEndian.Uint64(t.Data[pos*8:])))
+	case DTypeUint8:
+		return float64(t.Data[pos])
+	case DTypeFloat16:
+		return float64(float16ToFloat32(binary.LittleEndian.Uint16(t.Data[pos*2:])))
+	default:
+		panic("unknown dtype: " + t.DType.String())
+	}
+}
+
+func (t *Tensor) set(pos int, value float64) {
+	switch t.DType {
+	case DTypeFloat32:
+		binary.LittleEndian.PutUint32(t.Data[pos*4:], math.Float32bits(float32(value)))
+	case DTypeFloat64:
+		binary.LittleEndian.PutUint64(t.Data[pos*8:], math.Float64bits(value))
+	case DTypeInt32:
+		binary.LittleEndian.PutUint32(t.Data[pos*4:], uint32(int32(value)))
+	case DTypeInt64:
+		binary.LittleEndian.PutUint64(t.Data[pos*8:], uint64(int64(value)))
+	case DTypeUint8:
+		t.Data[pos] = uint8(value)
+	case DTypeFloat16:
+		binary.LittleEndian.PutUint16(t.Data[pos*2:], float32ToFloat16(float32(value)))
+	default:
+		panic("unknown dtype: " + t.DType.String())
+	}
+}
+
+// float16ToFloat32 converts an IEEE 754 half-precision
+// number to a float32.
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | frac<<13)
+	case exp != 0:
+		return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+	case frac == 0:
+		return math.Float32frombits(sign)
+	default:
+		// Subnormal numbers are normalized.
+		exp = 113
+		for frac&0x400 == 0 {
+			frac <<= 1
+			exp--
+		}
+		return math.Float32frombits(sign | exp<<23 | (frac&0x3ff)<<13)
+	}
+}
+
+// float32ToFloat16 converts a float32 to the nearest IEEE
+// 754 half-precision number, rounding ties to even.
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	frac := bits & 0x7fffff
+	if exp == 0xff {
+		if frac != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+	exp -= 127 - 15
+	if exp >= 0x1f {
+		return sign | 0x7c00
+	}
+	var shift uint
+	if exp <= 0 {
+		if exp < -10 {
+			return sign
+		}
+		frac |= 0x800000
+		shift = uint(14 - exp)
+		exp = 0
+	} else {
+		shift = 13
+	}
+	half := uint32(1) << (shift - 1)
+	rest := frac & (1<<shift - 1)
+	frac >>= shift
+	if rest > half || (rest == half && frac&1 != 0) {
+		frac++
+	}
+	// A carry out of the fraction increments the exponent,
+	// possibly producing infinity.
+	return sign | (uint16(exp<<10) + uint16(frac))
+}
@@ -0,0 +1,157 @@
+package serializer
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTensorSerialize(t *testing.T) {
+	for _, dtype := range []DType{DTypeFloat32, DTypeFloat64, DTypeInt32, DTypeInt64,
+		DTypeUint8, DTypeFloat16} {
+		tensor := NewTensor(dtype, 2, 3)
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 3; j++ {
+				tensor.Set(float64(i*3+j), i, j)
+			}
+		}
+		data, err := SerializeWithType(tensor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DeserializeWithType(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, tensor) {
+			t.Errorf("%s: expected %v but got %v", dtype, tensor, decoded)
+		}
+		expected := Float64Slice{0, 1, 2, 3, 4, 5}
+		if actual := decoded.(*Tensor).Float64Slice(); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: expected %v but got %v", dtype, expected, actual)
+		}
+
+		payload, _ := tensor.Serialize()
+		if _, err := DeserializeTensor(payload[:len(payload)-1]); err == nil {
+			t.Errorf("%s: expected error for truncated data", dtype)
+		}
+	}
+
+	scalar := NewTensor(DTypeFloat64)
+	scalar.Set(3)
+	data, _ := scalar.Serialize()
+	decoded, err := DeserializeTensor(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if decoded.Shape != nil || decoded.At() != 3 {
+		t.Errorf("unexpected scalar: %v", decoded)
+	}
+
+	huge := []byte{byte(DTypeUint8), 2, 0, 0, 0, 0, 0, 0, 0}
+	huge = append(huge, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0)
+	if _, err := DeserializeTensor(huge); err == nil {
+		t.Error("expected error for huge shape")
+	}
+
+	overflow := []byte{byte(DTypeUint8), 2, 0, 0, 0, 0, 0, 0, 0}
+	overflow = append(overflow, 0, 0, 0, 0, 0, 0, 0, 0)
+	overflow = append(overflow, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	if res, err := DeserializeTensor(overflow); err == nil {
+		t.Errorf("expected error for overflowing shape but got %v", res.Shape)
+	}
+}
+
+func TestTensorViews(t *testing.T) {
+	tensor, err := TensorFromFloat32Slice(Float32Slice{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+		3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TensorFromFloat32Slice(Float32Slice{1, 2, 3}, 2, 2); err == nil {
+		t.Error("expected shape error")
+	}
+
+	view := tensor.Slice(1, 1, 3).Slice(0, 1, 3)
+	if view.IsContiguous() {
+		t.Error("view should not be contiguous")
+	}
+	expected := Float32Slice{5, 6, 9, 10}
+	if actual := view.Float32Slice(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+	view.Set(-1, 0, 0)
+	if tensor.At(1, 1) != -1 {
+		t.Error("view should share data")
+	}
+
+	transposed := tensor.Transpose()
+	if !reflect.DeepEqual(transposed.Shape, []int{4, 3}) || transposed.At(3, 2) != 11 ||
+		transposed.At(1, 1) != -1 {
+		t.Errorf("unexpected transpose: %v", transposed)
+	}
+
+	data, err := transposed.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DeserializeTensor(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Float32Slice(), transposed.Float32Slice()) ||
+		!decoded.IsContiguous() || !reflect.DeepEqual(decoded.Shape, []int{4, 3}) {
+		t.Errorf("unexpected decoded tensor: %v", decoded)
+	}
+
+	empty := tensor.Slice(0, 2, 2)
+	if n := empty.NumElements(); n != 0 || len(empty.Contiguous().Data) != 0 {
+		t.Errorf("unexpected empty tensor: %v", empty)
+	}
+
+	bad := &Tensor{DType: DTypeFloat64, Shape: []int{3}, Data: make([]byte, 16)}
+	if _, err := bad.Serialize(); err == nil {
+		t.Error("expected error for out-of-bounds tensor")
+	}
+}
+
+func TestTensorConvert(t *testing.T) {
+	tensor, _ := TensorFromFloat64Slice(Float64Slice{1.5, -2, 65504, 1e-7, 300})
+	half := tensor.Convert(DTypeFloat16)
+	expected := Float64Slice{1.5, -2, 65504, float64(float16ToFloat32(2)), 300}
+	if actual := half.Float64Slice(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+	if actual := tensor.Convert(DTypeInt32).Float64Slice(); actual[0] != 1 || actual[1] != -2 {
+		t.Errorf("unexpected int32 conversion: %v", actual)
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	for h := 0; h < 1<<16; h++ {
+		f := float16ToFloat32(uint16(h))
+		if math.IsNaN(float64(f)) {
+			if !math.IsNaN(float64(float16ToFloat32(float32ToFloat16(f)))) {
+				t.Errorf("NaN %#x did not round trip", h)
+			}
+			continue
+		}
+		if actual := float32ToFloat16(f); actual != uint16(h) {
+			t.Errorf("%#x -> %v -> %#x", h, f, actual)
+		}
+	}
+	cases := map[float32]uint16{
+		1:                       0x3c00,
+		65504:                   0x7bff,
+		65520:                   0x7c00,
+		float32(math.Inf(-1)):   0xfc00,
+		1 + 1.0/2048:            0x3c00,
+		1 + 3.0/2048:            0x3c02,
+		5.960464477539063e-08:   0x0001,
+		2.98023223876953125e-08: 0x0000,
+	}
+	for f, expected := range cases {
+		if actual := float32ToFloat16(f); actual != expected {
+			t.Errorf("%v: expected %#x but got %#x", f, expected, actual)
+		}
+	}
+}
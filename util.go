@@ -5,8 +5,9 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"math/big"
 	"reflect"
+	"time"
 
 	"github.com/unixpickle/essentials"
 )
@@ -19,8 +20,9 @@ var (
 )
 
 func init() {
-	RegisterDeserializer(slice(nil).SerializerType(), func(d []byte) (Serializer, error) {
-		res, err := DeserializeSlice(d)
+	RegisterContextDeserializer(slice(nil).SerializerType(), func(d []byte,
+		ctx *DecodeContext) (Serializer, error) {
+		res, err := ctx.DeserializeSlice(d)
 		if err != nil {
 			return nil, err
 		}
@@ -33,44 +35,118 @@ func init() {
 // the given Serializer.
 // This is meant to be used with DeserializeWithType.
 func SerializeWithType(s Serializer) ([]byte, error) {
-	data, err := s.Serialize()
+	return AppendWithType(nil, s)
+}
+
+// AppendWithType is like SerializeWithType, but it
+// appends the result to dst and returns the extended
+// buffer.
+//
+// If s is an Appender, its data is appended directly to
+// dst without an intermediate copy.
+func AppendWithType(dst []byte, s Serializer) ([]byte, error) {
+	typeID := s.SerializerType()
+	dst = growBytes(dst, 4+len(typeID))
+	dst = helperByteOrder.AppendUint32(dst, uint32(len(typeID)))
+	dst = append(dst, typeID...)
+
+	var err error
+	if appender, ok := s.(Appender); ok {
+		dst, err = appender.AppendSerialize(dst)
+	} else {
+		var data []byte
+		data, err = s.Serialize()
+		dst = append(dst, data...)
+	}
 	if err != nil {
 		return nil, essentials.AddCtx("serialize with type", err)
 	}
-	typeData := []byte(s.SerializerType())
-
-	res := make([]byte, len(data)+len(typeData)+4)
-	copy(res[len(typeData)+4:], data)
-	copy(res[4:], typeData)
-	helperByteOrder.PutUint32(res, uint32(len(typeData)))
-
-	return res, nil
+	return dst, nil
 }
 
 // DeserializeWithType performs the inverse of
 // SerializeWithType, first decoding the type ID and
 // then using that type ID to decode the object.
-func DeserializeWithType(d []byte) (obj Serializer, err error) {
+//
+// Type IDs are looked up in the DefaultRegistry.
+func DeserializeWithType(d []byte) (Serializer, error) {
+	return DefaultRegistry.DeserializeWithType(d)
+}
+
+// DeserializeWithType is like the global
+// DeserializeWithType, but it looks up type IDs in r.
+//
+// Nested objects, such as the elements of []Serializer
+// and Map values, are also decoded using r, provided that
+// their ContextDeserializers use the DecodeContext.
+// Wrapper types with a registered Unwrapper are unwrapped
+// automatically.
+// If the type ID has no Deserializer in r, registered
+// aliases and migrations are used to find one.
+func (r *Registry) DeserializeWithType(d []byte) (Serializer, error) {
+	return r.deserializeWithType(d, 0)
+}
+
+// deserializeWithType implements DeserializeWithType,
+// tracking the nesting depth for DecodeLimits.
+func (r *Registry) deserializeWithType(d []byte, depth int) (obj Serializer, err error) {
 	defer func() {
 		err = essentials.AddCtx("deserialize with type", err)
 	}()
 
-	if len(d) < 4 {
-		return nil, ErrBufferUnderflow
+	limits := r.GetDecodeLimits()
+	if err := limits.checkBytes(uint64(len(d))); err != nil {
+		return nil, err
+	}
+	if err := limits.checkDepth(depth); err != nil {
+		return nil, err
+	}
+
+	typeID, data, err := SplitWithType(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.checkTypeIDLength(len(typeID)); err != nil {
+		return nil, err
 	}
 
-	size := int(helperByteOrder.Uint32(d))
-	if size+4 > len(d) {
-		return nil, ErrBufferUnderflow
+	ctx := &DecodeContext{registry: r, depth: depth}
+	if unwrapper := r.getContextUnwrapper(typeID); unwrapper != nil {
+		inner, err := unwrapper(data, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ctx.DeserializeWithType(inner)
 	}
-	typeID := string(d[4 : size+4])
 
-	deserializer := GetDeserializer(typeID)
-	if deserializer == nil {
-		return nil, errors.New("unregistered type ID: " + typeID)
+	deserializer, data, err := r.resolve(typeID, data)
+	if err != nil {
+		return nil, err
 	}
+	obj, err = deserializer(data, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.checkObject(obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
 
-	return deserializer(d[4+size:])
+// SplitWithType splits the output of SerializeWithType
+// into the type ID and the serialized object, without
+// decoding the object.
+//
+// The returned data is a sub-slice of d.
+func SplitWithType(d []byte) (typeID string, data []byte, err error) {
+	if len(d) < 4 {
+		return "", nil, ErrBufferUnderflow
+	}
+	size := uint64(helperByteOrder.Uint32(d))
+	if size+4 > uint64(len(d)) {
+		return "", nil, ErrBufferUnderflow
+	}
+	return string(d[4 : size+4]), d[4+size:], nil
 }
 
 // SerializeSlice serializes a slice of Serializers,
@@ -78,41 +154,98 @@ func DeserializeWithType(d []byte) (obj Serializer, err error) {
 // This is meant to be used in conjunction with
 // DeserializeSlice.
 func SerializeSlice(s []Serializer) ([]byte, error) {
-	var res bytes.Buffer
+	return AppendSlice(nil, s)
+}
 
+// AppendSlice is like SerializeSlice, but it appends the
+// result to dst and returns the extended buffer.
+func AppendSlice(dst []byte, s []Serializer) ([]byte, error) {
 	for _, x := range s {
-		serialized, err := SerializeWithType(x)
+		sizeOffset := len(dst)
+		dst = append(dst, make([]byte, 8)...)
+		var err error
+		dst, err = AppendWithType(dst, x)
 		if err != nil {
 			return nil, essentials.AddCtx("serialize slice", err)
 		}
-		binary.Write(&res, helperByteOrder, uint64(len(serialized)))
-		res.Write(serialized)
+		helperByteOrder.PutUint64(dst[sizeOffset:], uint64(len(dst)-(sizeOffset+8)))
 	}
+	return dst, nil
+}
 
-	return res.Bytes(), nil
+// SplitSlice splits the output of SerializeSlice into the
+// SerializeWithType encodings of the elements, without
+// decoding them.
+//
+// The returned elements are sub-slices of d.
+func SplitSlice(d []byte) (elems [][]byte, err error) {
+	for len(d) >= 8 {
+		size := helperByteOrder.Uint64(d)
+		d = d[8:]
+		if size > uint64(len(d)) {
+			return nil, ErrBufferUnderflow
+		}
+		elems = append(elems, d[:size])
+		d = d[size:]
+	}
+	if len(d) != 0 {
+		return nil, ErrResidualData
+	}
+	return elems, nil
 }
 
 // DeserializeSlice does the inverse of SerializeSlice.
-func DeserializeSlice(d []byte) (objs []Serializer, err error) {
+//
+// Type IDs are looked up in the DefaultRegistry.
+func DeserializeSlice(d []byte) ([]Serializer, error) {
+	return DefaultRegistry.DeserializeSlice(d)
+}
+
+// DeserializeSlice is like the global DeserializeSlice,
+// but it looks up type IDs in r.
+func (r *Registry) DeserializeSlice(d []byte) ([]Serializer, error) {
+	return r.deserializeSlice(d, 0)
+}
+
+// deserializeSlice implements DeserializeSlice, tracking
+// the nesting depth for DecodeLimits.
+func (r *Registry) deserializeSlice(d []byte, depth int) (objs []Serializer, err error) {
 	defer func() {
 		err = essentials.AddCtx("deserialize slice", err)
 	}()
 
+	limits := r.GetDecodeLimits()
+	if err := limits.checkBytes(uint64(len(d))); err != nil {
+		return nil, err
+	}
+	if err := limits.checkDepth(depth); err != nil {
+		return nil, err
+	}
+
+	zeroCopy := r.isZeroCopy()
 	buf := bytes.NewBuffer(d)
 	var res []Serializer
 
 	for buf.Len() >= 8 {
+		if err := limits.checkElements(uint64(len(res) + 1)); err != nil {
+			return nil, err
+		}
+
 		var nextLen64 uint64
 		binary.Read(buf, helperByteOrder, &nextLen64)
-		nextLen := int(nextLen64)
-		if nextLen > buf.Len() {
+		if nextLen64 > uint64(buf.Len()) {
 			return nil, ErrBufferUnderflow
 		}
 
-		nextData := make([]byte, nextLen)
-		buf.Read(nextData)
+		var nextData []byte
+		if zeroCopy {
+			nextData = buf.Next(int(nextLen64))
+		} else {
+			nextData = make([]byte, int(nextLen64))
+			buf.Read(nextData)
+		}
 
-		obj, err := DeserializeWithType(nextData)
+		obj, err := r.deserializeWithType(nextData, depth)
 		if err != nil {
 			return nil, err
 		}
@@ -145,49 +278,30 @@ func DeserializeSlice(d []byte) (objs []Serializer, err error) {
 //     float32
 //     []float32
 //     bool
+//     int8, int16, uint, uint8, uint16, uint32, uint64
+//     []int8, []int16, []uint, []uint16, []uint32, []uint64
+//     complex64, complex128
+//     []complex64, []complex128
+//     []string, []bool
+//     [][]float32, [][]float64, [][]int
+//     time.Time, time.Duration
+//     *big.Int, *big.Float, *big.Rat
 //     []Serializer
+//     map[string]Serializer
+//     map[string]T, where T is any supported type
 //
+// Maps are serialized as a Map.
+// DeserializeAny can decode a Map into any map with string
+// keys and supported value types.
 func SerializeAny(obj ...interface{}) (data []byte, err error) {
 	defer func() {
 		err = essentials.AddCtx("SerializeAny", err)
 	}()
 	s := make([]Serializer, len(obj))
 	for i, x := range obj {
-		var ok bool
-		s[i], ok = x.(Serializer)
-		if !ok {
-			switch x := x.(type) {
-			case string:
-				s[i] = String(x)
-			case []byte:
-				s[i] = Bytes(x)
-			case int:
-				s[i] = Int(x)
-			case []int:
-				s[i] = IntSlice(x)
-			case int32:
-				s[i] = Int32(x)
-			case int64:
-				s[i] = Int64(x)
-			case []int32:
-				s[i] = Int32Slice(x)
-			case []int64:
-				s[i] = Int64Slice(x)
-			case float64:
-				s[i] = Float64(x)
-			case []float64:
-				s[i] = Float64Slice(x)
-			case float32:
-				s[i] = Float32(x)
-			case []float32:
-				s[i] = Float32Slice(x)
-			case bool:
-				s[i] = Bool(x)
-			case []Serializer:
-				s[i] = slice(x)
-			default:
-				return nil, fmt.Errorf("unsupported type %T", x)
-			}
+		s[i], err = toSerializer(x)
+		if err != nil {
+			return nil, err
 		}
 	}
 	return SerializeSlice(s)
@@ -200,11 +314,25 @@ func SerializeAny(obj ...interface{}) (data []byte, err error) {
 //
 // If necessary, objects are converted to the desired type
 // (e.g. []byte can be converted to string).
-func DeserializeAny(data []byte, out ...interface{}) (err error) {
+//
+// Type IDs are looked up in the DefaultRegistry.
+func DeserializeAny(data []byte, out ...interface{}) error {
+	return DefaultRegistry.DeserializeAny(data, out...)
+}
+
+// DeserializeAny is like the global DeserializeAny, but
+// it looks up type IDs in r.
+func (r *Registry) DeserializeAny(data []byte, out ...interface{}) error {
+	return r.deserializeAny(data, 0, out...)
+}
+
+// deserializeAny implements DeserializeAny, tracking the
+// nesting depth for DecodeLimits.
+func (r *Registry) deserializeAny(data []byte, depth int, out ...interface{}) (err error) {
 	defer func() {
 		err = essentials.AddCtx("DeserializeAny", err)
 	}()
-	slice, err := DeserializeSlice(data)
+	slice, err := r.deserializeSlice(data, depth)
 	if err != nil {
 		return err
 	}
@@ -213,19 +341,8 @@ func DeserializeAny(data []byte, out ...interface{}) (err error) {
 			len(out), len(slice))
 	}
 	for i, obj := range slice {
-		val := reflect.ValueOf(obj)
-		destVal := reflect.ValueOf(out[i])
-		if destVal.Kind() != reflect.Ptr {
-			return fmt.Errorf("element %d: expected pointer but got %T",
-				i, out[i])
-		}
-		if val.Type().AssignableTo(destVal.Type().Elem()) {
-			destVal.Elem().Set(val)
-		} else if val.Type().ConvertibleTo(destVal.Type().Elem()) {
-			destVal.Elem().Set(val.Convert(destVal.Type().Elem()))
-		} else {
-			return fmt.Errorf("element %d: expecting %s but decoded %T",
-				i, destVal.Type().Elem(), obj)
+		if err := assignDecoded(obj, out[i]); err != nil {
+			return fmt.Errorf("element %d: %s", i, err)
 		}
 	}
 	return nil
@@ -234,29 +351,142 @@ func DeserializeAny(data []byte, out ...interface{}) (err error) {
 // SaveAny writes the given objects to a file.
 // It is like using SerializeAny and writing the results
 // to a file afterward.
-func SaveAny(path string, obj ...interface{}) (err error) {
-	defer func() {
-		err = essentials.AddCtx("SaveAny", err)
-	}()
-	enc, err := SerializeAny(obj...)
-	if err != nil {
-		return err
-	}
-	return ioutil.WriteFile(path, enc, 0755)
+//
+// The file is replaced atomically, as described in
+// SaveAnyWithOptions.
+func SaveAny(path string, obj ...interface{}) error {
+	return SaveAnyWithOptions(path, nil, obj...)
 }
 
 // LoadAny loads the given objects from a file.
 // It is like using DeserializeAny, but first reading the
 // data from a file.
-func LoadAny(path string, objOut ...interface{}) (err error) {
-	defer func() {
-		err = essentials.AddCtx("LoadAny", err)
-	}()
-	contents, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
+//
+// Both raw files (written by SaveAny) and containers
+// (written by SaveContainer) are supported.
+func LoadAny(path string, objOut ...interface{}) error {
+	return LoadAnyWithOptions(path, nil, objOut...)
+}
+
+// toSerializer converts an object into a Serializer,
+// wrapping supported built-in types as needed.
+func toSerializer(x interface{}) (Serializer, error) {
+	if s, ok := x.(Serializer); ok {
+		return s, nil
+	}
+	switch x := x.(type) {
+	case string:
+		return String(x), nil
+	case []byte:
+		return Bytes(x), nil
+	case int:
+		return Int(x), nil
+	case []int:
+		return IntSlice(x), nil
+	case int32:
+		return Int32(x), nil
+	case int64:
+		return Int64(x), nil
+	case []int32:
+		return Int32Slice(x), nil
+	case []int64:
+		return Int64Slice(x), nil
+	case float64:
+		return Float64(x), nil
+	case []float64:
+		return Float64Slice(x), nil
+	case float32:
+		return Float32(x), nil
+	case []float32:
+		return Float32Slice(x), nil
+	case bool:
+		return Bool(x), nil
+	case int8:
+		return Int8(x), nil
+	case []int8:
+		return Int8Slice(x), nil
+	case int16:
+		return Int16(x), nil
+	case []int16:
+		return Int16Slice(x), nil
+	case uint:
+		return Uint(x), nil
+	case []uint:
+		return UintSlice(x), nil
+	case uint8:
+		return Uint8(x), nil
+	case uint16:
+		return Uint16(x), nil
+	case []uint16:
+		return Uint16Slice(x), nil
+	case uint32:
+		return Uint32(x), nil
+	case []uint32:
+		return Uint32Slice(x), nil
+	case uint64:
+		return Uint64(x), nil
+	case []uint64:
+		return Uint64Slice(x), nil
+	case complex64:
+		return Complex64(x), nil
+	case []complex64:
+		return Complex64Slice(x), nil
+	case complex128:
+		return Complex128(x), nil
+	case []complex128:
+		return Complex128Slice(x), nil
+	case []string:
+		return StringSlice(x), nil
+	case []bool:
+		return BoolSlice(x), nil
+	case [][]float32:
+		return Float32Slice2D(x), nil
+	case [][]float64:
+		return Float64Slice2D(x), nil
+	case [][]int:
+		return IntSlice2D(x), nil
+	case time.Time:
+		return Time(x), nil
+	case time.Duration:
+		return Duration(x), nil
+	case *big.Int:
+		return (*BigInt)(x), nil
+	case *big.Float:
+		return (*BigFloat)(x), nil
+	case *big.Rat:
+		return (*BigRat)(x), nil
+	case []Serializer:
+		return slice(x), nil
+	case map[string]Serializer:
+		return Map(x), nil
+	}
+	if val := reflect.ValueOf(x); val.Kind() == reflect.Map &&
+		val.Type().Key().Kind() == reflect.String {
+		return mapToSerializer(val)
+	}
+	return nil, fmt.Errorf("unsupported type %T", x)
+}
+
+// assignDecoded stores a decoded object in the variable
+// pointed to by out, converting it if necessary.
+func assignDecoded(obj Serializer, out interface{}) error {
+	val := reflect.ValueOf(obj)
+	destVal := reflect.ValueOf(out)
+	if destVal.Kind() != reflect.Ptr {
+		return fmt.Errorf("expected pointer but got %T", out)
+	}
+	if val.Type().AssignableTo(destVal.Type().Elem()) {
+		destVal.Elem().Set(val)
+	} else if val.Type().ConvertibleTo(destVal.Type().Elem()) {
+		destVal.Elem().Set(val.Convert(destVal.Type().Elem()))
+	} else if m, ok := obj.(Map); ok && destVal.Elem().Kind() == reflect.Map &&
+		destVal.Elem().Type().Key().Kind() == reflect.String {
+		return assignMap(m, destVal.Elem())
+	} else {
+		return fmt.Errorf("expecting %s but decoded %T",
+			destVal.Type().Elem(), obj)
 	}
-	return DeserializeAny(contents, objOut...)
+	return nil
 }
 
 type slice []Serializer
@@ -265,6 +495,36 @@ func (s slice) Serialize() ([]byte, error) {
 	return SerializeSlice(s)
 }
 
+func (s slice) AppendSerialize(dst []byte) ([]byte, error) {
+	return AppendSlice(dst, s)
+}
+
 func (s slice) SerializerType() string {
 	return "[]Serializer"
 }
+
+func (s slice) Copy() (interface{}, error) {
+	if s == nil {
+		return s, nil
+	}
+	res := make(slice, len(s))
+	for i, x := range s {
+		var err error
+		res[i], err = copySerializer(x)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// growBytes ensures that n more bytes can be appended to
+// the buffer without reallocating.
+func growBytes(buf []byte, n int) []byte {
+	if cap(buf)-len(buf) >= n {
+		return buf
+	}
+	res := make([]byte, len(buf), len(buf)+n)
+	copy(res, buf)
+	return res
+}
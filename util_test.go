@@ -161,3 +161,30 @@ func ExampleSerializeAny() {
 
 	// Output: 15 3.14
 }
+
+func TestSplitSlice(t *testing.T) {
+	data, err := SerializeAny(Int(3), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	elems, err := SplitSlice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elems) != 2 {
+		t.Fatalf("expected 2 elements but got %d", len(elems))
+	}
+	for i, expected := range []Serializer{Int(3), String("hello")} {
+		typeID, payload, err := SplitWithType(elems[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedPayload, _ := expected.Serialize()
+		if typeID != expected.SerializerType() || !bytes.Equal(payload, expectedPayload) {
+			t.Errorf("element %d: unexpected result %s %v", i, typeID, payload)
+		}
+	}
+	if _, err := SplitSlice(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
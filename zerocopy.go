@@ -0,0 +1,231 @@
+package serializer
+
+import (
+	"unsafe"
+
+	"github.com/unixpickle/essentials"
+)
+
+// littleEndianHost is true if the host stores numbers in
+// little-endian order, matching the wire format.
+var littleEndianHost = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+func init() {
+	RegisterContextDeserializer(Float32Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeFloat32Slice, ViewFloat32Slice))
+	RegisterContextDeserializer(Float64Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeFloat64Slice, ViewFloat64Slice))
+	RegisterContextDeserializer(Int32Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeInt32Slice, ViewInt32Slice))
+	RegisterContextDeserializer(Int64Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeInt64Slice, ViewInt64Slice))
+	RegisterContextDeserializer(Int8Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeInt8Slice, ViewInt8Slice))
+	RegisterContextDeserializer(Int16Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeInt16Slice, ViewInt16Slice))
+	RegisterContextDeserializer(Uint16Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeUint16Slice, ViewUint16Slice))
+	RegisterContextDeserializer(Uint32Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeUint32Slice, ViewUint32Slice))
+	RegisterContextDeserializer(Uint64Slice(nil).SerializerType(),
+		viewDeserializer(DeserializeUint64Slice, ViewUint64Slice))
+}
+
+// viewDeserializer creates a ContextDeserializer which
+// uses a typed view function in zero-copy mode and a
+// typed deserializer otherwise.
+func viewDeserializer(deserialize, view interface{}) ContextDeserializer {
+	deserializeFunc := typedDeserializer(deserialize)
+	viewFunc := typedDeserializer(view)
+	return func(d []byte, ctx *DecodeContext) (Serializer, error) {
+		if ctx.ZeroCopy() {
+			return viewFunc(d)
+		}
+		return deserializeFunc(d)
+	}
+}
+
+// SetZeroCopy enables or disables zero-copy decoding.
+//
+// With zero-copy decoding, the fixed-size numeric slices
+// (Float32Slice, Float64Slice, Int8Slice, Int16Slice,
+// Int32Slice, Int64Slice, Uint16Slice, Uint32Slice, and
+// Uint64Slice) are decoded with the ViewXXX functions
+// (unless their deserializers have been replaced in r),
+// and the elements of []Serializer values are not copied
+// before they are decoded.
+// As a result, decoded objects (including Bytes) may share
+// memory with the input data, which must not be modified
+// while they are in use.
+func (r *Registry) SetZeroCopy(flag bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.zeroCopy = flag
+}
+
+func (r *Registry) isZeroCopy() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.zeroCopy
+}
+
+// ZeroCopy returns true if zero-copy decoding is enabled
+// for the Registry in use.
+// See Registry.SetZeroCopy.
+func (c *DecodeContext) ZeroCopy() bool {
+	return c.registry.isZeroCopy()
+}
+
+// ViewFloat32Slice is like DeserializeFloat32Slice, but
+// the result may share memory with d.
+//
+// Memory is shared when the host is little-endian and the
+// elements in d are suitably aligned.
+// Otherwise, the elements are copied.
+//
+// The wire format has no padding, so whether the elements
+// are aligned depends on where the object lands in the
+// encoded data (for example, on the lengths of the type
+// IDs and objects which precede it).
+func ViewFloat32Slice(d []byte) (Float32Slice, error) {
+	body, n, err := viewData(d, 4)
+	if err != nil {
+		return nil, essentials.AddCtx("view []float32", err)
+	}
+	if n > 0 && canView(body, 4) {
+		return unsafe.Slice((*float32)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeFloat32Slice(d)
+}
+
+// ViewFloat64Slice is like DeserializeFloat64Slice, but
+// the result may share memory with d.
+// See ViewFloat32Slice for details.
+func ViewFloat64Slice(d []byte) (Float64Slice, error) {
+	body, n, err := viewData(d, 8)
+	if err != nil {
+		return nil, essentials.AddCtx("view []float64", err)
+	}
+	if n > 0 && canView(body, 8) {
+		return unsafe.Slice((*float64)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeFloat64Slice(d)
+}
+
+// ViewInt32Slice is like DeserializeInt32Slice, but the
+// result may share memory with d.
+// See ViewFloat32Slice for details.
+func ViewInt32Slice(d []byte) (Int32Slice, error) {
+	body, n, err := viewData(d, 4)
+	if err != nil {
+		return nil, essentials.AddCtx("view []int32", err)
+	}
+	if n > 0 && canView(body, 4) {
+		return unsafe.Slice((*int32)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeInt32Slice(d)
+}
+
+// ViewInt64Slice is like DeserializeInt64Slice, but the
+// result may share memory with d.
+// See ViewFloat32Slice for details.
+func ViewInt64Slice(d []byte) (Int64Slice, error) {
+	body, n, err := viewData(d, 8)
+	if err != nil {
+		return nil, essentials.AddCtx("view []int64", err)
+	}
+	if n > 0 && canView(body, 8) {
+		return unsafe.Slice((*int64)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeInt64Slice(d)
+}
+
+// ViewInt8Slice is like DeserializeInt8Slice, but the
+// result may share memory with d.
+// See ViewFloat32Slice for details.
+func ViewInt8Slice(d []byte) (Int8Slice, error) {
+	body, n, err := viewData(d, 1)
+	if err != nil {
+		return nil, essentials.AddCtx("view []int8", err)
+	}
+	if n > 0 && canView(body, 1) {
+		return unsafe.Slice((*int8)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeInt8Slice(d)
+}
+
+// ViewInt16Slice is like DeserializeInt16Slice, but the
+// result may share memory with d.
+// See ViewFloat32Slice for details.
+func ViewInt16Slice(d []byte) (Int16Slice, error) {
+	body, n, err := viewData(d, 2)
+	if err != nil {
+		return nil, essentials.AddCtx("view []int16", err)
+	}
+	if n > 0 && canView(body, 2) {
+		return unsafe.Slice((*int16)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeInt16Slice(d)
+}
+
+// ViewUint16Slice is like DeserializeUint16Slice, but the
+// result may share memory with d.
+// See ViewFloat32Slice for details.
+func ViewUint16Slice(d []byte) (Uint16Slice, error) {
+	body, n, err := viewData(d, 2)
+	if err != nil {
+		return nil, essentials.AddCtx("view []uint16", err)
+	}
+	if n > 0 && canView(body, 2) {
+		return unsafe.Slice((*uint16)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeUint16Slice(d)
+}
+
+// ViewUint32Slice is like DeserializeUint32Slice, but the
+// result may share memory with d.
+// See ViewFloat32Slice for details.
+func ViewUint32Slice(d []byte) (Uint32Slice, error) {
+	body, n, err := viewData(d, 4)
+	if err != nil {
+		return nil, essentials.AddCtx("view []uint32", err)
+	}
+	if n > 0 && canView(body, 4) {
+		return unsafe.Slice((*uint32)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeUint32Slice(d)
+}
+
+// ViewUint64Slice is like DeserializeUint64Slice, but the
+// result may share memory with d.
+// See ViewFloat32Slice for details.
+func ViewUint64Slice(d []byte) (Uint64Slice, error) {
+	body, n, err := viewData(d, 8)
+	if err != nil {
+		return nil, essentials.AddCtx("view []uint64", err)
+	}
+	if n > 0 && canView(body, 8) {
+		return unsafe.Slice((*uint64)(unsafe.Pointer(&body[0])), n), nil
+	}
+	return DeserializeUint64Slice(d)
+}
+
+// viewData decodes the size of a numeric slice and returns
+// the bytes of its elements.
+func viewData(d []byte, elemSize int) (body []byte, n int, err error) {
+	if len(d) < 8 {
+		return nil, 0, ErrBufferUnderflow
+	}
+	n, err = checkElementCount(helperByteOrder.Uint64(d), elemSize, len(d)-8)
+	if err != nil {
+		return nil, 0, err
+	}
+	return d[8 : 8+n*elemSize], n, nil
+}
+
+func canView(body []byte, align uintptr) bool {
+	return littleEndianHost && uintptr(unsafe.Pointer(&body[0]))%align == 0
+}
@@ -0,0 +1,141 @@
+package serializer
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestViews(t *testing.T) {
+	f32 := Float32Slice{1, -2, 3.5}
+	f64 := Float64Slice{1, -2, 3.5, 1e100}
+	i32 := Int32Slice{1, -2, 1 << 30}
+	i64 := Int64Slice{1, -2, 1 << 60}
+	i8 := Int8Slice{1, -2, 127}
+	i16 := Int16Slice{1, -2, 1 << 14}
+	u16 := Uint16Slice{1, 2, 1 << 15}
+	u32 := Uint32Slice{1, 2, 1 << 31}
+	u64 := Uint64Slice{1, 2, 1 << 63}
+
+	r := DefaultRegistry.Clone()
+	r.SetZeroCopy(true)
+	for offset := 0; offset < 8; offset++ {
+		for _, obj := range []Serializer{f32, f64, i32, i64, i8, i16, u16, u32, u64,
+			Float32Slice{}} {
+			data, _ := obj.Serialize()
+			buf := make([]byte, len(data)+offset)
+			copy(buf[offset:], data)
+			data = buf[offset:]
+
+			view, err := r.Get(obj.SerializerType())(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(view, obj) {
+				t.Errorf("offset %d: expected %v but got %v", offset, obj, view)
+			}
+		}
+	}
+
+	data, _ := f64.Serialize()
+	view, err := ViewFloat64Slice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := uintptr(unsafe.Pointer(&view[0])) == uintptr(unsafe.Pointer(&data[8]))
+	if shared != canView(data[8:], 8) {
+		t.Errorf("unexpected sharing: %v", shared)
+	}
+
+	if _, err := ViewFloat64Slice(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+
+	data, _ = u16.Serialize()
+	u16View, err := ViewUint16Slice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared = uintptr(unsafe.Pointer(&u16View[0])) == uintptr(unsafe.Pointer(&data[8]))
+	if shared != canView(data[8:], 2) {
+		t.Errorf("unexpected uint16 sharing: %v", shared)
+	}
+	if _, err := ViewUint16Slice(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated uint16 data")
+	}
+}
+
+func TestRegistryZeroCopy(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	r.SetZeroCopy(true)
+
+	objs := []interface{}{Float32Slice{1, 2}, []Serializer{Int64Slice{3, 4}}, "hi"}
+	data, err := SerializeAny(objs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out1 Float32Slice
+	var out2 []Serializer
+	var out3 string
+	if err := r.DeserializeAny(data, &out1, &out2, &out3); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]interface{}{out1, out2, out3},
+		[]interface{}{Float32Slice{1, 2}, []Serializer{Int64Slice{3, 4}}, "hi"}) {
+		t.Errorf("unexpected result: %v %v %v", out1, out2, out3)
+	}
+}
+
+func TestZeroCopySharing(t *testing.T) {
+	if !littleEndianHost {
+		t.Skip("views are never shared on big-endian hosts")
+	}
+	r := DefaultRegistry.Clone()
+	r.SetZeroCopy(true)
+
+	// The []float32 elements start at offset 48, while the
+	// []float64 elements start at offset 106.
+	data, err := SerializeAny("x", Float32Slice{1, 2}, "abc", Float64Slice{3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s1, s2 string
+	var f32 Float32Slice
+	var f64 Float64Slice
+	if err := r.DeserializeAny(data, &s1, &f32, &s2, &f64); err != nil {
+		t.Fatal(err)
+	}
+	if &f32[0] != (*float32)(unsafe.Pointer(&data[48])) {
+		t.Error("aligned []float32 was copied")
+	}
+	start := uintptr(unsafe.Pointer(&data[0]))
+	if p := uintptr(unsafe.Pointer(&f64[0])); p >= start && p < start+uintptr(len(data)) {
+		t.Error("unaligned []float64 was shared")
+	}
+	if !reflect.DeepEqual(f64, Float64Slice{3, 4}) {
+		t.Errorf("unexpected []float64: %v", f64)
+	}
+
+	r.Update(f32.SerializerType(), func(d []byte) (Serializer, error) {
+		return DeserializeFloat32Slice(d)
+	})
+	if err := r.DeserializeAny(data, &s1, &f32, &s2, &f64); err != nil {
+		t.Fatal(err)
+	}
+	if &f32[0] == (*float32)(unsafe.Pointer(&data[48])) {
+		t.Error("registry override was ignored")
+	}
+}
+
+func BenchmarkFloat32View(b *testing.B) {
+	buf := make([]float32, 1000000)
+	for i := range buf {
+		buf[i] = rand.Float32()
+	}
+	data, _ := Float32Slice(buf).Serialize()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ViewFloat32Slice(data)
+	}
+}